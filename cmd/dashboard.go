@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"http-diff/cmd/task"
+	"http-diff/lib/dashboard"
+)
+
+// dashboardRefreshInterval 控制台进度的刷新间隔
+const dashboardRefreshInterval = 500 * time.Millisecond
+
+// runDashboard 在控制台原地刷新所有任务的进度，任务全部结束之后输出最终进度并关闭 done
+func runDashboard(dispatcher *task.Dispatcher, done chan struct{}) {
+	defer close(done)
+
+	board := dashboard.New(os.Stdout)
+	ticker := time.NewTicker(dashboardRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-dispatcher.Done():
+			board.Render(dispatcher.Progress())
+			return
+		case <-ticker.C:
+			board.Render(dispatcher.Progress())
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"http-diff/cmd/task"
+	"http-diff/lib/importer"
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+)
+
+// importConfig import 命令的参数
+var importConfig = struct {
+	Format      string
+	Input       string
+	Output      string
+	Methods     string
+	PathPattern string
+	StripPrefix string
+}{}
+
+func init() {
+	initImportFlag()
+	rootCmd.AddCommand(importCmd)
+}
+
+func initImportFlag() {
+	flags := importCmd.PersistentFlags()
+
+	flags.StringVarP(&importConfig.Format, "format", "f", "", "抓包数据格式 har、curl、access_log，默认根据输入文件扩展名判断，.har 为 har，其余为 access_log")
+	flags.StringVarP(&importConfig.Input, "input", "i", "", "抓包数据文件，.gz 和 .zst 文件自动解压")
+	flags.StringVarP(&importConfig.Output, "output", "o", "", "请求参数文件，默认输出到控制台，.gz 和 .zst 结尾时自动压缩")
+	flags.StringVarP(&importConfig.Methods, "method", "m", "", "只导入指定的请求方法，多个用英文逗号分隔")
+	flags.StringVarP(&importConfig.PathPattern, "path", "p", "", "只导入路径匹配该正则表达式的请求")
+	flags.StringVarP(&importConfig.StripPrefix, "strip_prefix", "s", "", "去掉请求路径的前缀，url_a 和 url_b 中已经包含该前缀时使用")
+
+	_ = importCmd.MarkPersistentFlagRequired("input")
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "把 HAR、curl 命令和访问日志转换为请求参数文件",
+	Long:  "读取 HAR 文件、curl 命令列表或 nginx combined 格式的访问日志，按请求方法和路径过滤之后转换为 payload 文件格式，每行一个请求参数",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := strings.ToLower(importConfig.Format)
+		if format == "" {
+			format = importer.FormatAccessLog
+			if strings.HasSuffix(strings.TrimSuffix(strings.TrimSuffix(importConfig.Input, ".gz"), ".zst"), ".har") {
+				format = importer.FormatHar
+			}
+		}
+
+		methods := make(map[string]struct{})
+		for _, method := range strings.Split(importConfig.Methods, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				methods[strings.ToUpper(method)] = struct{}{}
+			}
+		}
+
+		var pathPattern *regexp.Regexp
+		if importConfig.PathPattern != "" {
+			pattern, err := regexp.Compile(importConfig.PathPattern)
+			if err != nil {
+				return errors.New("invalid path pattern: " + err.Error())
+			}
+			pathPattern = pattern
+		}
+
+		reader, err := util.OpenReader(importConfig.Input)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		var writer io.Writer = os.Stdout
+		var outputFile io.WriteCloser
+		if importConfig.Output != "" {
+			outputFile, err = util.OpenWriter(importConfig.Output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+			if err != nil {
+				return err
+			}
+			writer = outputFile
+		}
+
+		imported, filtered := 0, 0
+		skipped, err := importer.Read(format, reader, func(request *importer.Request) error {
+			if _, ok := methods[request.Method]; len(methods) > 0 && !ok {
+				filtered++
+				return nil
+			}
+
+			if pathPattern != nil && !pathPattern.MatchString(request.Path) {
+				filtered++
+				return nil
+			}
+
+			requestPath := request.Path
+			if importConfig.StripPrefix != "" {
+				requestPath = strings.TrimPrefix(requestPath, importConfig.StripPrefix)
+			}
+
+			payload, err := task.NewPayload(request.Method, requestPath, request.RawQuery, request.Headers, request.Body)
+			if err != nil {
+				return err
+			}
+
+			marshal, err := sonic.Marshal(payload)
+			if err != nil {
+				return err
+			}
+
+			if _, err = io.WriteString(writer, string(marshal)+"\n"); err != nil {
+				return err
+			}
+
+			imported++
+			return nil
+		})
+		// 压缩文件关闭时才会写入剩余的数据
+		if outputFile != nil {
+			if errInner := outputFile.Close(); err == nil {
+				err = errInner
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "imported: %d, filtered: %d, skipped: %d\n", imported, filtered, skipped)
+		return nil
+	},
+}
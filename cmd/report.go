@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"http-diff/cmd/task"
+
+	"github.com/spf13/cobra"
+)
+
+var htmlReportConfig = task.HtmlReportConfig{}
+
+func init() {
+	initReportFlag()
+	rootCmd.AddCommand(reportCmd)
+}
+
+func initReportFlag() {
+	flags := reportCmd.PersistentFlags()
+
+	flags.StringVarP(&htmlReportConfig.TaskName, "name", "n", "", "任务名称")
+	flags.StringVarP(&htmlReportConfig.WorkDir, "work_dir", "w", "./", "任务的工作目录")
+	flags.StringVarP(&htmlReportConfig.HtmlFile, "output", "o", "", "报告文件路径，默认为工作目录中的 {任务名}_report.html")
+	flags.IntVarP(&htmlReportConfig.Limit, "limit", "l", task.DefaultHtmlReportLimit, "最多展示的差异和错误数量")
+
+	_ = reportCmd.MarkPersistentFlagRequired("name")
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "根据任务的对比结果生成 HTML 报告",
+	Long:  "读取任务工作目录中的对比结果文件和错误信息文件，生成静态 HTML 报告",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		htmlFile, err := task.GenerateHtmlReport(htmlReportConfig)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("report file:", htmlFile)
+		return nil
+	},
+}
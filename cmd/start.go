@@ -3,19 +3,37 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"http-diff/cmd/task"
+	"http-diff/constant"
 	"http-diff/lib/config"
+	"http-diff/lib/dashboard"
 	"http-diff/lib/http"
 	"http-diff/lib/logger"
+	"http-diff/lib/metrics"
 	"http-diff/lib/safe"
 	"http-diff/lib/signal"
+	"http-diff/util"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
+// defaultShutdownGracePeriod 默认的停止等待时间
+const defaultShutdownGracePeriod = 30 * time.Second
+
+// defaultBufferSize 默认的通道缓冲区大小
+const defaultBufferSize = 100000
+
+// defaultMetricsPath 默认的指标访问路径
+const defaultMetricsPath = "/metrics"
+
 var configFile = ""
+var resume = false
+var showDashboard = false
 var cfg = &config.Configs{}
 
 func init() {
@@ -27,6 +45,8 @@ func initStartFlag() {
 	flags := startCmd.PersistentFlags()
 
 	flags.StringVarP(&configFile, "config", "c", "./config/config.toml", "配置文件")
+	flags.BoolVarP(&resume, "resume", "r", false, "从上次保存的断点继续运行任务，结果追加写入已有的文件")
+	flags.BoolVarP(&showDashboard, "dashboard", "d", false, "在控制台实时展示任务进度，标准输出不是终端时在日志中记录统计信息")
 }
 
 var startCmd = &cobra.Command{
@@ -39,6 +59,12 @@ var startCmd = &cobra.Command{
 			return err
 		}
 
+		// 控制台展示进度时日志只写入文件，避免打乱进度的刷新
+		dashboardEnabled := showDashboard && dashboard.IsTerminal(os.Stdout)
+		if dashboardEnabled {
+			cfg.LoggerConfig.Console = false
+		}
+
 		logger.Init("Http-Diff", cfg.LoggerConfig)
 
 		http.Init(cfg.FastHttp)
@@ -54,27 +80,96 @@ var startCmd = &cobra.Command{
 
 		logger.Info(ctx, "http-diff started")
 
-		dispatcher, err := task.NewDispatcher(ctx, cfg.DiffConfigs)
+		if showDashboard && !dashboardEnabled {
+			logger.Warn(ctx, "http-diff stdout is not a terminal, fall back to log statistics info")
+			for index := range cfg.DiffConfigs {
+				cfg.DiffConfigs[index].LogStatistics = true
+			}
+		}
+
+		dispatcher, err := task.NewDispatcher(ctx, cfg.DiffConfigs, resume)
 		if err != nil {
 			logger.Error(ctx, "failed to create task dispatcher", zap.Error(err))
 			return err
 		}
 
+		if cfg.Metrics.Address != "" {
+			go safe.RecoveryWithLogger(func() { serveMetrics(ctx) }, ctx, "Metrics_Serve")
+		}
+
 		// 启动任务
 		go safe.RecoveryWithLogger(dispatcher.Start, ctx, "Dispatcher_Start")
 
+		var dashboardDone chan struct{}
+		if dashboardEnabled {
+			dashboardDone = make(chan struct{})
+			go safe.RecoveryWithLogger(func() { runDashboard(dispatcher, dashboardDone) }, ctx, "Dashboard_Run")
+		}
+
 		//等待程序运行结束或者接收到终止信号
+		signalCh := signal.ReceiveShutdownSignal()
 		select {
 		case <-dispatcher.Done():
 			logger.Info(ctx, "http-diff stopped, all tasks completed")
-		case sig := <-signal.ReceiveShutdownSignal():
+		case sig := <-signalCh:
 			logger.Info(ctx, "http-diff received shutdown signal", zap.String("signal", sig.String()))
+			shutdown(ctx, cancelFunc, dispatcher, signalCh)
+		}
+
+		// 等待控制台输出最终的进度
+		if dashboardDone != nil {
+			<-dashboardDone
 		}
 
+		_ = logger.Flush()
 		return nil
 	},
 }
 
+// shutdown 停止所有任务，等待正在处理的请求完成并把结果写入文件
+//
+// 超过等待时间之后取消上下文强制结束任务，再次收到终止信号时直接退出程序
+func shutdown(ctx context.Context, cancelFunc context.CancelFunc, dispatcher *task.Dispatcher, signalCh chan os.Signal) {
+	gracePeriod := cfg.App.ShutdownGracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = defaultShutdownGracePeriod
+	}
+
+	logger.Info(ctx, "http-diff shutting down, waiting for running requests", zap.Duration("gracePeriod", gracePeriod))
+	dispatcher.Shutdown()
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-dispatcher.Done():
+			logger.Info(ctx, "http-diff stopped gracefully")
+			return
+		case <-timer.C:
+			logger.Warn(ctx, "http-diff shutdown grace period expired, cancel running tasks", zap.Duration("gracePeriod", gracePeriod))
+			cancelFunc()
+		case sig := <-signalCh:
+			logger.Warn(ctx, "http-diff received shutdown signal again, force exit", zap.String("signal", sig.String()))
+			_ = logger.Flush()
+			os.Exit(1)
+		}
+	}
+}
+
+// serveMetrics 启动指标服务，启动失败时只记录日志，不影响任务运行
+func serveMetrics(ctx context.Context) {
+	metricsPath := cfg.Metrics.Path
+	if metricsPath == "" {
+		metricsPath = defaultMetricsPath
+	}
+
+	logger.Info(ctx, "http-diff metrics server started", zap.String("address", cfg.Metrics.Address), zap.String("path", metricsPath))
+	if err := metrics.Default.Serve(ctx, cfg.Metrics.Address, metricsPath); err != nil {
+		logger.Error(ctx, "http-diff metrics server stopped with error", zap.String("address", cfg.Metrics.Address), zap.Error(err))
+	}
+}
+
 // 验证参数
 func validateDiffConfig(diffConfigs []config.DiffConfig) ([]config.DiffConfig, error) {
 	if len(diffConfigs) == 0 {
@@ -102,19 +197,86 @@ func validateDiffConfig(diffConfigs []config.DiffConfig) ([]config.DiffConfig, e
 			diffConfig.Concurrency = 1
 		}
 
+		if diffConfig.InputBufferSize <= 0 {
+			diffConfig.InputBufferSize = defaultBufferSize
+		}
+
+		if diffConfig.OutputBufferSize <= 0 {
+			diffConfig.OutputBufferSize = defaultBufferSize
+		}
+
 		if diffConfig.WorkDir == "" {
 			return nil, fmt.Errorf("diff config work_dir cannot be empty,index:[%d], config detial:[%v]", index, diffConfig)
 		}
 
-		if diffConfig.Payload == "" {
+		// 使用请求参数模板时不读取 payload 文件
+		if diffConfig.Payload == "" && diffConfig.PayloadTemplate == "" {
 			return nil, fmt.Errorf("diff config payload cannot be empty,index:[%d], config detial:[%v]", index, diffConfig)
 		}
 
-		if diffConfig.UrlA == "" {
+		if diffConfig.Payload != "" && diffConfig.PayloadTemplate != "" {
+			return nil, fmt.Errorf("diff config payload and payload_template cannot be set at the same time,index:[%d], config detial:[%v]", index, diffConfig)
+		}
+
+		if diffConfig.AdaptiveConcurrency {
+			if diffConfig.MinConcurrency <= 0 {
+				diffConfig.MinConcurrency = 1
+			}
+
+			if diffConfig.MinConcurrency > diffConfig.Concurrency {
+				return nil, fmt.Errorf("diff config min_concurrency cannot be greater than concurrency,index:[%d], config detial:[%v]", index, diffConfig)
+			}
+
+			if diffConfig.AdaptiveMaxErrorRate <= 0 {
+				diffConfig.AdaptiveMaxErrorRate = 0.05
+			}
+
+			if diffConfig.AdaptiveMaxLatency <= 0 {
+				diffConfig.AdaptiveMaxLatency = time.Second
+			}
+
+			if diffConfig.AdaptiveInterval <= 0 {
+				diffConfig.AdaptiveInterval = 5 * time.Second
+			}
+		}
+
+		if diffConfig.LatencyRatio < 0 {
+			return nil, fmt.Errorf("diff config latency_ratio cannot be negative,index:[%d], config detial:[%v]", index, diffConfig)
+		}
+
+		if diffConfig.QPS < 0 || diffConfig.UrlAQPS < 0 || diffConfig.UrlBQPS < 0 {
+			return nil, fmt.Errorf("diff config qps cannot be negative,index:[%d], config detial:[%v]", index, diffConfig)
+		}
+
+		if diffConfig.Burst < 0 || diffConfig.UrlABurst < 0 || diffConfig.UrlBBurst < 0 {
+			return nil, fmt.Errorf("diff config burst cannot be negative,index:[%d], config detial:[%v]", index, diffConfig)
+		}
+
+		diffConfig.CompressOutput = strings.ToLower(diffConfig.CompressOutput)
+		if diffConfig.CompressOutput != "" && diffConfig.CompressOutput != util.CompressionGzip && diffConfig.CompressOutput != util.CompressionZstd {
+			return nil, fmt.Errorf("diff config compress_output is not supported: %s,index:[%d], config detial:[%v]", diffConfig.CompressOutput, index, diffConfig)
+		}
+
+		if diffConfig.Mode == "" {
+			diffConfig.Mode = constant.ModeDiff
+		}
+
+		diffConfig.Mode = strings.ToLower(diffConfig.Mode)
+		if diffConfig.Mode != constant.ModeDiff && diffConfig.Mode != constant.ModeRecord && diffConfig.Mode != constant.ModeReplay {
+			return nil, fmt.Errorf("diff config mode is not supported: %s,index:[%d], config detial:[%v]", diffConfig.Mode, index, diffConfig)
+		}
+
+		if diffConfig.RecordFile == "" {
+			diffConfig.RecordFile = diffConfig.Name + "_record.txt"
+		}
+
+		// 回放模式下接口A的响应来自录制文件
+		if diffConfig.UrlA == "" && diffConfig.Mode != constant.ModeReplay {
 			return nil, fmt.Errorf("diff config url_a cannot be empty,index:[%d], config detial:[%v]", index, diffConfig)
 		}
 
-		if diffConfig.UrlB == "" {
+		// 录制模式下只请求接口A
+		if diffConfig.UrlB == "" && diffConfig.Mode != constant.ModeRecord {
 			return nil, fmt.Errorf("diff config url_b cannot be empty,index:[%d], config detial:[%v]", index, diffConfig)
 		}
 
@@ -122,8 +284,42 @@ func validateDiffConfig(diffConfigs []config.DiffConfig) ([]config.DiffConfig, e
 			return nil, fmt.Errorf("diff config method cannot be empty,index:[%d], config detial:[%v]", index, diffConfig)
 		}
 
+		diffConfig.Method = strings.ToUpper(diffConfig.Method)
+		if !isSupportedMethod(diffConfig.Method) {
+			return nil, fmt.Errorf("diff config method is not supported: %s,index:[%d], config detial:[%v]", diffConfig.Method, index, diffConfig)
+		}
+
+		if diffConfig.ResponseFormat == "" {
+			diffConfig.ResponseFormat = constant.ResponseFormatJson
+		}
+
+		diffConfig.ResponseFormat = strings.ToLower(diffConfig.ResponseFormat)
+		if !isSupportedResponseFormat(diffConfig.ResponseFormat) {
+			return nil, fmt.Errorf("diff config response_format is not supported: %s,index:[%d], config detial:[%v]", diffConfig.ResponseFormat, index, diffConfig)
+		}
+
 		result = append(result, diffConfig)
 	}
 
 	return result, nil
 }
+
+// isSupportedMethod 判断请求方法是否支持
+func isSupportedMethod(method string) bool {
+	switch method {
+	case constant.GET, constant.POST, constant.PUT, constant.PATCH, constant.DELETE, constant.HEAD:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSupportedResponseFormat 判断响应数据格式是否支持
+func isSupportedResponseFormat(responseFormat string) bool {
+	switch responseFormat {
+	case constant.ResponseFormatJson, constant.ResponseFormatText, constant.ResponseFormatXml, constant.ResponseFormatRaw:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package task
+
+import (
+	"time"
+
+	"http-diff/lib/logger"
+
+	"go.uber.org/zap"
+)
+
+// waitActive 等待编号为 index 的协程可以处理请求，任务停止时返回 false
+func (t *Task) waitActive(index int) bool {
+	for {
+		active, changed := t.adaptiveLimiter.Active(index)
+		if active {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-t.shutdownCh:
+			return false
+		case <-t.ctx.Done():
+			return false
+		}
+	}
+}
+
+// currentConcurrency 当前的并发数
+func (t *Task) currentConcurrency() int {
+	if t.adaptiveLimiter == nil {
+		return t.Config.Concurrency
+	}
+
+	return t.adaptiveLimiter.Limit()
+}
+
+// adjustConcurrencyLoop 定时根据错误率和 p95 耗时调整并发数
+func (t *Task) adjustConcurrencyLoop() {
+	tick := time.NewTicker(t.Config.AdaptiveInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-t.Done():
+			return
+		case <-tick.C:
+			before := t.adaptiveLimiter.Limit()
+			after, statistics := t.adaptiveLimiter.Adjust()
+			if before != after {
+				logger.Info(t.ctx, "Task_adjustConcurrencyLoop Concurrency changed",
+					zap.String("task", t.Config.TaskName),
+					zap.Int("before", before),
+					zap.Int("after", after),
+					zap.Int64("count", statistics.Count),
+					zap.Float64("errorRate", statistics.ErrorRate),
+					zap.Duration("p95Latency", statistics.P95Latency),
+				)
+			}
+		}
+	}
+}
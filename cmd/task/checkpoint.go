@@ -0,0 +1,556 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"http-diff/constant"
+	"http-diff/lib/logger"
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// checkpointInterval 保存断点的时间间隔
+const checkpointInterval = 5 * time.Second
+
+// 请求参数的处理结果，用于统计断点中的数量
+const (
+	resultSame = iota
+	resultDiff
+	resultFailed
+	resultRecorded
+)
+
+// Checkpoint 任务断点，记录每个请求参数文件已经处理完成的行、对应的统计信息和结果文件的行数
+type Checkpoint struct {
+	Files       []*FileCheckpoint `json:"files"`
+	SameCount   int64             `json:"sameCount"`
+	DiffCount   int64             `json:"diffCount"`
+	FailedCount int64             `json:"failedCount"`
+	// RecordedCount 录制模式下录制成功的数量
+	RecordedCount int64 `json:"recordedCount,omitempty"`
+	// ResultLines 保存断点时每个结果文件的行数，key 为相对于工作目录的文件路径，断点续跑时结果文件只保留这些行
+	ResultLines map[string]int `json:"resultLines"`
+	UpdatedAt   string         `json:"updatedAt"`
+}
+
+// FileCheckpoint 单个请求参数文件的断点
+type FileCheckpoint struct {
+	// File 请求参数文件，和 payload 配置中的文件名一致
+	File string `json:"file"`
+	// Line 已经处理完成的行数，请求是并发处理的，只有从第一行开始连续处理完成的行才会被计入
+	Line int `json:"line"`
+	// Done Line 之后已经处理完成的行号，断点续跑时跳过这些行
+	Done []int `json:"done,omitempty"`
+}
+
+// checkpointTracker 记录请求参数的处理进度
+type checkpointTracker struct {
+	lock       sync.Mutex
+	checkpoint *Checkpoint
+	// pending 已经处理完成，但是前面还有没处理完成的行，下标和文件对应，key 为行号
+	pending []map[int]struct{}
+	// changed 上次保存之后是否有新的进度
+	changed bool
+}
+
+func newCheckpointTracker(files []string) *checkpointTracker {
+	tracker := &checkpointTracker{
+		checkpoint: &Checkpoint{Files: make([]*FileCheckpoint, 0, len(files)), ResultLines: make(map[string]int)},
+		pending:    make([]map[int]struct{}, 0, len(files)),
+	}
+
+	for _, file := range files {
+		tracker.checkpoint.Files = append(tracker.checkpoint.Files, &FileCheckpoint{File: file})
+		tracker.pending = append(tracker.pending, make(map[int]struct{}))
+	}
+
+	return tracker
+}
+
+// restore 从上次保存的断点恢复进度，按文件名匹配，不在当前任务中的文件会被忽略
+func (c *checkpointTracker) restore(checkpoint *Checkpoint) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	files := make(map[string]*FileCheckpoint, len(checkpoint.Files))
+	for _, file := range checkpoint.Files {
+		files[file.File] = file
+	}
+
+	for index, file := range c.checkpoint.Files {
+		file.Line = 0
+		c.pending[index] = make(map[int]struct{})
+
+		restoreFile, ok := files[file.File]
+		if !ok {
+			continue
+		}
+
+		file.Line = restoreFile.Line
+		for _, lineNumber := range restoreFile.Done {
+			if lineNumber > file.Line {
+				c.pending[index][lineNumber] = struct{}{}
+			}
+		}
+	}
+
+	c.checkpoint.SameCount = checkpoint.SameCount
+	c.checkpoint.DiffCount = checkpoint.DiffCount
+	c.checkpoint.FailedCount = checkpoint.FailedCount
+	c.checkpoint.RecordedCount = checkpoint.RecordedCount
+	// 旧版本的断点没有记录结果文件的行数，为 nil 时保留结果文件中已有的数据
+	c.checkpoint.ResultLines = checkpoint.ResultLines
+}
+
+// processedLine 文件中从第一行开始连续处理完成的行数
+func (c *checkpointTracker) processedLine(fileIndex int) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.checkpoint.Files[fileIndex].Line
+}
+
+// processed 文件中的一行是否已经处理完成，断点续跑时跳过处理完成的行
+func (c *checkpointTracker) processed(fileIndex int, lineNumber int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if lineNumber <= c.checkpoint.Files[fileIndex].Line {
+		return true
+	}
+
+	_, ok := c.pending[fileIndex][lineNumber]
+	return ok
+}
+
+// resultLines 上次保存断点时结果文件的行数，断点中没有记录结果文件的行数时返回 false
+func (c *checkpointTracker) resultLines(name string) (int, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.checkpoint.ResultLines == nil {
+		return 0, false
+	}
+
+	return c.checkpoint.ResultLines[name], true
+}
+
+// done 标记一行处理完成，处理结果已经写入结果文件，立即计入统计信息
+func (c *checkpointTracker) done(fileIndex int, lineNumber int, result int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if fileIndex < 0 || fileIndex >= len(c.checkpoint.Files) || lineNumber <= 0 {
+		return
+	}
+
+	file := c.checkpoint.Files[fileIndex]
+	pending := c.pending[fileIndex]
+	if _, ok := pending[lineNumber]; ok || lineNumber <= file.Line {
+		return
+	}
+
+	pending[lineNumber] = struct{}{}
+	c.changed = true
+
+	switch result {
+	case resultSame:
+		c.checkpoint.SameCount++
+	case resultDiff:
+		c.checkpoint.DiffCount++
+	case resultFailed:
+		c.checkpoint.FailedCount++
+	case resultRecorded:
+		c.checkpoint.RecordedCount++
+	}
+
+	for {
+		if _, ok := pending[file.Line+1]; !ok {
+			break
+		}
+
+		delete(pending, file.Line+1)
+		file.Line++
+	}
+}
+
+// marshal 序列化断点，resultLines 为此时已经打开的结果文件的行数，没有新的进度时返回 nil
+func (c *checkpointTracker) marshal(resultLines map[string]int) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.changed {
+		return nil, nil
+	}
+
+	for index, file := range c.checkpoint.Files {
+		file.Done = make([]int, 0, len(c.pending[index]))
+		for lineNumber := range c.pending[index] {
+			file.Done = append(file.Done, lineNumber)
+		}
+		sort.Ints(file.Done)
+	}
+
+	// 还没有打开的结果文件保留上次记录的行数
+	if c.checkpoint.ResultLines == nil {
+		c.checkpoint.ResultLines = make(map[string]int, len(resultLines))
+	}
+	for name, lines := range resultLines {
+		c.checkpoint.ResultLines[name] = lines
+	}
+
+	c.checkpoint.UpdatedAt = time.Now().Format(time.DateTime)
+	marshal, err := sonic.Marshal(c.checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	c.changed = false
+	return marshal, nil
+}
+
+// setResultLines 设置结果文件的行数
+func (c *checkpointTracker) setResultLines(name string, lines int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.checkpoint.ResultLines == nil {
+		c.checkpoint.ResultLines = make(map[string]int)
+	}
+	c.checkpoint.ResultLines[name] = lines
+}
+
+// checkpointFilePath 断点文件路径
+func (t *Task) checkpointFilePath() string {
+	return path.Join(t.Config.WorkDir, t.Config.TaskName+"_checkpoint.json")
+}
+
+// loadCheckpoint 读取上次保存的断点，断点文件不存在时从头开始处理
+func (t *Task) loadCheckpoint() error {
+	filePath := t.checkpointFilePath()
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logger.Warn(t.ctx, "Task_loadCheckpoint Checkpoint file not found, start from the beginning", zap.String("filePath", filePath))
+			return nil
+		}
+
+		logger.Error(t.ctx, "Task_loadCheckpoint Failed to read checkpoint file", zap.String("filePath", filePath), zap.Error(err))
+		return err
+	}
+
+	checkpoint := &Checkpoint{}
+	err = sonic.Unmarshal(content, checkpoint)
+	if err != nil {
+		logger.Error(t.ctx, "Task_loadCheckpoint Failed to unmarshal checkpoint", zap.String("filePath", filePath), zap.Error(err))
+		return err
+	}
+
+	t.checkpoint.restore(checkpoint)
+	t.statisticsInfo.Restore(checkpoint.SameCount, checkpoint.DiffCount, checkpoint.FailedCount, checkpoint.RecordedCount)
+
+	logger.Info(t.ctx, "Task_loadCheckpoint Resume from checkpoint", zap.String("filePath", filePath), zap.Any("checkpoint", checkpoint))
+	return nil
+}
+
+// saveCheckpoint 保存断点，先写临时文件再重命名，避免程序中断时断点文件不完整
+func (t *Task) saveCheckpoint() {
+	filePath := t.checkpointFilePath()
+
+	marshal, err := t.marshalCheckpoint()
+	if err != nil {
+		logger.Error(t.ctx, "Task_saveCheckpoint Failed to marshal checkpoint", zap.String("filePath", filePath), zap.Error(err))
+		return
+	}
+
+	if marshal == nil {
+		return
+	}
+
+	tempFilePath := filePath + ".tmp"
+	err = os.WriteFile(tempFilePath, marshal, 0644)
+	if err != nil {
+		logger.Error(t.ctx, "Task_saveCheckpoint Failed to write checkpoint file", zap.String("filePath", tempFilePath), zap.Error(err))
+		return
+	}
+
+	err = os.Rename(tempFilePath, filePath)
+	if err != nil {
+		logger.Error(t.ctx, "Task_saveCheckpoint Failed to rename checkpoint file", zap.String("filePath", filePath), zap.Error(err))
+	}
+}
+
+// marshalCheckpoint 刷新结果文件并序列化断点，期间不能写结果文件，保证断点中的进度和结果文件的行数一致
+func (t *Task) marshalCheckpoint() ([]byte, error) {
+	t.resultLock.Lock()
+	defer t.resultLock.Unlock()
+
+	// 压缩器会缓冲数据，刷新之后结果文件中才有断点记录的所有行
+	if err := t.flushResultFiles(); err != nil {
+		return nil, err
+	}
+
+	t.resultFilesLock.Lock()
+	resultLines := make(map[string]int, len(t.resultFiles))
+	for _, file := range t.resultFiles {
+		resultLines[file.name] = file.lineCount()
+	}
+	t.resultFilesLock.Unlock()
+
+	return t.checkpoint.marshal(resultLines)
+}
+
+// saveCheckpointLoop 定时保存断点
+func (t *Task) saveCheckpointLoop() {
+	tick := time.NewTicker(checkpointInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-t.Done():
+			return
+		case <-tick.C:
+			t.saveCheckpoint()
+		}
+	}
+}
+
+// finishPayload 请求参数的处理结果已经写入文件，更新断点
+func (t *Task) finishPayload(payload *Payload, result int) {
+	if payload != nil {
+		t.checkpoint.done(payload.fileIndex, payload.lineNumber, result)
+	}
+
+	t.waitGroup.Done()
+}
+
+// resultFile 结果文件，压缩器会缓冲数据，保存断点之前需要先刷新，否则程序中断时断点中已经完成的结果可能没有写入文件
+type resultFile struct {
+	lock   sync.Mutex
+	writer io.WriteCloser
+	closed bool
+	// name 相对于工作目录的文件路径
+	name string
+	// lines 文件的行数，包括断点续跑之前保留的行
+	lines int
+}
+
+func (f *resultFile) Write(p []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	n, err := f.writer.Write(p)
+	f.lines += bytes.Count(p[:n], []byte("\n"))
+	return n, err
+}
+
+// lineCount 已经写入的行数
+func (f *resultFile) lineCount() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.lines
+}
+
+// Flush 把缓冲的数据写入文件，文件关闭之后不再刷新
+func (f *resultFile) Flush() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return nil
+	}
+
+	if flusher, ok := f.writer.(util.Flusher); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+func (f *resultFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.closed = true
+	return f.writer.Close()
+}
+
+// openResultFile 打开结果文件，断点续跑时追加写入，否则清空文件，.gz 和 .zst 结尾的文件写入时自动压缩
+func (t *Task) openResultFile(filePath string) (io.WriteCloser, error) {
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	lines := 0
+	name := t.resultFileName(filePath)
+	if t.Config.Resume {
+		flag = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		// 任务初始化时结果文件已经截断到断点记录的行数
+		lines, _ = t.checkpoint.resultLines(name)
+	}
+
+	writer, err := util.OpenWriter(filePath, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &resultFile{writer: writer, name: name, lines: lines}
+
+	t.resultFilesLock.Lock()
+	t.resultFiles = append(t.resultFiles, file)
+	t.resultFilesLock.Unlock()
+
+	return file, nil
+}
+
+// resultFileName 结果文件相对于工作目录的路径，作为断点中记录行数的 key
+func (t *Task) resultFileName(filePath string) string {
+	name, err := filepath.Rel(t.Config.WorkDir, filePath)
+	if err != nil {
+		return filePath
+	}
+
+	return name
+}
+
+// resultFilePaths 任务写入的结果文件，录制模式下写录制文件，其他模式写对比结果文件
+func (t *Task) resultFilePaths() []string {
+	if t.Config.Mode == constant.ModeRecord {
+		return []string{t.recordFilePath(), t.resultFilePath("_failed_payload.txt")}
+	}
+
+	return []string{t.resultFilePath("_output.txt"), t.resultFilePath("_failed_payload.txt")}
+}
+
+// restoreResultFiles 断点续跑时把结果文件截断到断点记录的行数，之后的行对应的请求参数会被重新处理，避免结果重复
+//
+// 旧版本的断点没有记录结果文件的行数，保留文件中已有的数据
+func (t *Task) restoreResultFiles() error {
+	for _, filePath := range t.resultFilePaths() {
+		name := t.resultFileName(filePath)
+
+		lines, ok := t.checkpoint.resultLines(name)
+		if !ok {
+			count, err := util.FileLineCount(filePath)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			t.checkpoint.setResultLines(name, count)
+			logger.Warn(t.ctx, "Task_restoreResultFiles Checkpoint has no result lines, keep all lines", zap.String("filePath", filePath), zap.Int("lines", count))
+			continue
+		}
+
+		if err := truncateResultFile(filePath, lines); err != nil {
+			logger.Error(t.ctx, "Task_restoreResultFiles Failed to truncate result file", zap.String("filePath", filePath), zap.Int("lines", lines), zap.Error(err))
+			return err
+		}
+
+		logger.Info(t.ctx, "Task_restoreResultFiles Result file truncated to checkpoint", zap.String("filePath", filePath), zap.Int("lines", lines))
+	}
+
+	return nil
+}
+
+// truncateResultFile 只保留结果文件的前 lines 行，压缩文件解压之后重新压缩写入临时文件再重命名
+func truncateResultFile(filePath string, lines int) error {
+	if lines == 0 {
+		if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	reader, err := util.OpenReader(filePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// 临时文件保留压缩格式的扩展名
+	extension := util.CompressionExtension(util.FileCompression(filePath))
+	tempFilePath := strings.TrimSuffix(filePath, extension) + ".tmp" + extension
+
+	writer, err := util.OpenWriter(tempFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	bufferedReader := bufio.NewReader(reader)
+	count := 0
+	for count < lines {
+		line, err := bufferedReader.ReadBytes('\n')
+		if err != nil {
+			_ = writer.Close()
+			_ = os.Remove(tempFilePath)
+			return errors.New("result file " + filePath + " has " + strconv.Itoa(count) + " lines, checkpoint expects " + strconv.Itoa(lines) + ": " + err.Error())
+		}
+
+		if _, err = writer.Write(line); err != nil {
+			_ = writer.Close()
+			_ = os.Remove(tempFilePath)
+			return err
+		}
+		count++
+	}
+
+	if err = writer.Close(); err != nil {
+		_ = os.Remove(tempFilePath)
+		return err
+	}
+
+	return os.Rename(tempFilePath, filePath)
+}
+
+// flushResultFiles 把所有结果文件中缓冲的数据写入文件
+func (t *Task) flushResultFiles() error {
+	t.resultFilesLock.Lock()
+	defer t.resultFilesLock.Unlock()
+
+	for _, file := range t.resultFiles {
+		if err := file.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resultFilePath 结果文件路径，开启压缩时加上压缩格式的扩展名
+func (t *Task) resultFilePath(suffix string) string {
+	return path.Join(t.Config.WorkDir, t.Config.TaskName+suffix+util.CompressionExtension(t.Config.CompressOutput))
+}
+
+// restoreReport 断点续跑时把已有的对比结果汇总到差异报告中，并恢复性能差异的数量
+func (t *Task) restoreReport() error {
+	outputFilePath := t.resultFilePath("_output.txt")
+
+	return scanReportFile(outputFilePath, func(lineNumber int, line []byte) error {
+		output := &OutPut{}
+		if err := sonic.Unmarshal(line, output); err != nil {
+			logger.Warn(t.ctx, "Task_restoreReport Failed to unmarshal output", zap.String("filePath", outputFilePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			return nil
+		}
+
+		t.reportCollector.Add(output)
+		// 性能差异的结果都会写入文件，可以从结果文件中恢复数量
+		if output.PerformanceDiff {
+			t.statisticsInfo.AddPerformanceDiff()
+		}
+		return nil
+	})
+}
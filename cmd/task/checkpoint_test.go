@@ -0,0 +1,300 @@
+package task
+
+import (
+	"bufio"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointTrackerDone(t *testing.T) {
+	type doneLine struct {
+		fileIndex  int
+		lineNumber int
+		result     int
+	}
+
+	tests := []struct {
+		name   string
+		lines  []doneLine
+		want   []int
+		done   [][]int
+		counts [4]int64
+	}{
+		{
+			name:   "in order",
+			lines:  []doneLine{{0, 1, resultSame}, {0, 2, resultDiff}},
+			want:   []int{2, 0},
+			done:   [][]int{{}, {}},
+			counts: [4]int64{1, 1, 0, 0},
+		},
+		{
+			name:   "out of order",
+			lines:  []doneLine{{0, 3, resultFailed}, {0, 1, resultSame}, {0, 2, resultDiff}},
+			want:   []int{3, 0},
+			done:   [][]int{{}, {}},
+			counts: [4]int64{1, 1, 1, 0},
+		},
+		{
+			name:   "gap",
+			lines:  []doneLine{{0, 1, resultRecorded}, {0, 4, resultRecorded}, {0, 3, resultFailed}},
+			want:   []int{1, 0},
+			done:   [][]int{{3, 4}, {}},
+			counts: [4]int64{0, 0, 1, 2},
+		},
+		{
+			name:   "multiple files",
+			lines:  []doneLine{{1, 1, resultSame}, {0, 2, resultSame}, {1, 2, resultDiff}},
+			want:   []int{0, 2},
+			done:   [][]int{{2}, {}},
+			counts: [4]int64{2, 1, 0, 0},
+		},
+		{
+			name:   "duplicate and invalid lines",
+			lines:  []doneLine{{0, 1, resultSame}, {0, 1, resultSame}, {0, 3, resultDiff}, {0, 3, resultDiff}, {0, 0, resultSame}, {2, 1, resultSame}, {-1, 1, resultSame}},
+			want:   []int{1, 0},
+			done:   [][]int{{3}, {}},
+			counts: [4]int64{1, 1, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newCheckpointTracker([]string{"a.txt", "b.txt"})
+			for _, line := range tt.lines {
+				tracker.done(line.fileIndex, line.lineNumber, line.result)
+			}
+
+			marshal, err := tracker.marshal(nil)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			checkpoint := &Checkpoint{}
+			assert.Nil(t, sonic.Unmarshal(marshal, checkpoint))
+
+			for index, file := range checkpoint.Files {
+				assert.Equal(t, tt.want[index], file.Line)
+				assert.Equal(t, tt.want[index], tracker.processedLine(index))
+				if len(tt.done[index]) == 0 {
+					assert.Empty(t, file.Done)
+				} else {
+					assert.Equal(t, tt.done[index], file.Done)
+				}
+			}
+
+			assert.Equal(t, tt.counts, [4]int64{checkpoint.SameCount, checkpoint.DiffCount, checkpoint.FailedCount, checkpoint.RecordedCount})
+		})
+	}
+}
+
+func TestCheckpointTrackerRestore(t *testing.T) {
+	tracker := newCheckpointTracker([]string{"a.txt", "b.txt", "c.txt"})
+	tracker.restore(&Checkpoint{
+		Files: []*FileCheckpoint{
+			{File: "b.txt", Line: 2, Done: []int{1, 4, 6}},
+			{File: "a.txt", Line: 1},
+			{File: "removed.txt", Line: 10},
+		},
+		SameCount:   4,
+		DiffCount:   2,
+		FailedCount: 1,
+		ResultLines: map[string]int{"task_output.txt": 2},
+	})
+
+	assert.Equal(t, 1, tracker.processedLine(0))
+	assert.Equal(t, 2, tracker.processedLine(1))
+	assert.Equal(t, 0, tracker.processedLine(2))
+
+	processed := make([]int, 0)
+	for lineNumber := 1; lineNumber <= 7; lineNumber++ {
+		if tracker.processed(1, lineNumber) {
+			processed = append(processed, lineNumber)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 4, 6}, processed)
+	assert.False(t, tracker.processed(2, 1))
+
+	lines, ok := tracker.resultLines("task_output.txt")
+	assert.True(t, ok)
+	assert.Equal(t, 2, lines)
+	lines, ok = tracker.resultLines("task_failed_payload.txt")
+	assert.True(t, ok)
+	assert.Equal(t, 0, lines)
+
+	// 恢复之后完成的行从断点中的数量继续计数，跳过的行不再重复计数
+	tracker.done(1, 3, resultDiff)
+	tracker.done(1, 5, resultSame)
+	assert.Equal(t, 6, tracker.processedLine(1))
+
+	marshal, err := tracker.marshal(map[string]int{"task_output.txt": 3})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	checkpoint := &Checkpoint{}
+	assert.Nil(t, sonic.Unmarshal(marshal, checkpoint))
+	assert.Equal(t, int64(5), checkpoint.SameCount)
+	assert.Equal(t, int64(3), checkpoint.DiffCount)
+	assert.Equal(t, int64(1), checkpoint.FailedCount)
+	assert.Equal(t, map[string]int{"task_output.txt": 3}, checkpoint.ResultLines)
+
+	// 旧版本的断点没有记录结果文件的行数
+	tracker.restore(&Checkpoint{Files: []*FileCheckpoint{{File: "a.txt", Line: 3}}})
+	_, ok = tracker.resultLines("task_output.txt")
+	assert.False(t, ok)
+	assert.Equal(t, 0, tracker.processedLine(1))
+	assert.False(t, tracker.processed(1, 4))
+}
+
+func TestCheckpointTrackerMarshal(t *testing.T) {
+	tracker := newCheckpointTracker([]string{"a.txt"})
+
+	// 没有新的进度时不保存
+	marshal, err := tracker.marshal(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, marshal)
+
+	tracker.done(0, 5, resultSame)
+	tracker.done(0, 2, resultSame)
+	tracker.done(0, 1, resultDiff)
+
+	marshal, err = tracker.marshal(map[string]int{"task_output.txt": 1})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	checkpoint := &Checkpoint{}
+	assert.Nil(t, sonic.Unmarshal(marshal, checkpoint))
+	assert.Equal(t, []*FileCheckpoint{{File: "a.txt", Line: 2, Done: []int{5}}}, checkpoint.Files)
+	assert.Equal(t, map[string]int{"task_output.txt": 1}, checkpoint.ResultLines)
+	assert.NotEmpty(t, checkpoint.UpdatedAt)
+
+	marshal, err = tracker.marshal(map[string]int{"task_output.txt": 1})
+	assert.Nil(t, err)
+	assert.Nil(t, marshal)
+}
+
+func TestResumeTruncatesResultFiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		compressOutput string
+	}{
+		{name: "plain"},
+		{name: "gzip", compressOutput: util.CompressionGzip},
+		{name: "zstd", compressOutput: util.CompressionZstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+				writer.Header().Set("Content-Type", "application/json")
+				_, _ = writer.Write([]byte(`{"id":"` + request.URL.Query().Get("id") + `","path":"` + request.URL.Path + `"}`))
+			}))
+			defer server.Close()
+
+			workDir := t.TempDir()
+			writeTestFile(t, workDir, "payload.txt", `{"params":{"id":"1"}}`, `{"params":{"id":"2"}}`, `{"params":{"id":"3"}}`, `{"params":{"id":"4"}}`, `{"params":{"id":"5"}}`)
+
+			cfg := newTestConfig("resume", workDir, "payload.txt")
+			cfg.UrlA = server.URL + "/a"
+			cfg.UrlB = server.URL + "/b"
+			cfg.CompressOutput = tt.compressOutput
+			cfg.Resume = true
+
+			outputName := "resume_output.txt" + util.CompressionExtension(tt.compressOutput)
+
+			// 上次运行在保存断点时完成了第 1、2、4 行，之后又写入了第 3、5 行的结果，然后程序被强制结束
+			writeCompressedTestFile(t, path.Join(workDir, outputName),
+				newResumeTestOutput("1", true),
+				newResumeTestOutput("2", false),
+				newResumeTestOutput("4", false),
+				newResumeTestOutput("3", true),
+				newResumeTestOutput("5", false),
+			)
+			checkpoint, err := sonic.Marshal(&Checkpoint{
+				Files:       []*FileCheckpoint{{File: "payload.txt", Line: 2, Done: []int{4}}},
+				DiffCount:   3,
+				ResultLines: map[string]int{outputName: 3},
+			})
+			assert.Nil(t, err)
+			writeTestFile(t, workDir, "resume_checkpoint.json", string(checkpoint))
+
+			task := runTestTask(t, cfg)
+
+			assert.Equal(t, int64(5), task.statisticsInfo.GetDiffCount())
+			assert.Equal(t, int64(0), task.statisticsInfo.GetFailedCount())
+			assert.Equal(t, int64(1), task.statisticsInfo.GetPerformanceDiffCount())
+
+			ids := make([]string, 0)
+			for _, line := range readCompressedTestFile(t, path.Join(workDir, outputName)) {
+				output := &OutPut{}
+				if assert.Nil(t, sonic.UnmarshalString(line, output)) {
+					values, err := output.Payload.Params.values()
+					assert.Nil(t, err)
+					ids = append(ids, values.Get("id"))
+				}
+			}
+			sort.Strings(ids)
+			assert.Equal(t, []string{"1", "2", "3", "4", "5"}, ids)
+
+			content, err := os.ReadFile(path.Join(workDir, "resume_checkpoint.json"))
+			assert.Nil(t, err)
+			checkpointAfter := &Checkpoint{}
+			assert.Nil(t, sonic.Unmarshal(content, checkpointAfter))
+			assert.Equal(t, 5, checkpointAfter.Files[0].Line)
+			assert.Empty(t, checkpointAfter.Files[0].Done)
+			assert.Equal(t, int64(5), checkpointAfter.DiffCount)
+			assert.Equal(t, 5, checkpointAfter.ResultLines[outputName])
+		})
+	}
+}
+
+// newResumeTestOutput 断点续跑测试中上次运行写入的对比结果
+func newResumeTestOutput(id string, performanceDiff bool) string {
+	output := `{"payload":{"params":{"id":"` + id + `"},"headers":"","body":""},"diff":"-","latencyA":1,"latencyB":1`
+	if performanceDiff {
+		output += `,"performanceDiff":true`
+	}
+
+	return output + "}"
+}
+
+// writeCompressedTestFile 按照文件扩展名压缩写入文件，每个元素一行
+func writeCompressedTestFile(t *testing.T, filePath string, lines ...string) {
+	writer, err := util.OpenWriter(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	_, err = writer.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+}
+
+// readCompressedTestFile 按照文件扩展名解压读取文件，按行返回
+func readCompressedTestFile(t *testing.T, filePath string) []string {
+	reader, err := util.OpenReader(filePath)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer reader.Close()
+
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Nil(t, scanner.Err())
+
+	return lines
+}
@@ -0,0 +1,239 @@
+package task
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"http-diff/constant"
+	"http-diff/lib/logger"
+	"http-diff/util"
+
+	"go.uber.org/zap"
+)
+
+// compareNonJson 对比非 JSON 格式的响应数据，状态码不同时直接记录差异
+func (t *Task) compareNonJson(payload *Payload, urlAResponse, urlBResponse *Response, statusDiff, headerDiff string) {
+	urlABody, okA := urlAResponse.Body.([]byte)
+	urlBBody, okB := urlBResponse.Body.([]byte)
+	if (urlAResponse.Body != nil && !okA) || (urlBResponse.Body != nil && !okB) {
+		logger.Error(t.ctx, "Task_compareNonJson Response is not raw body", zap.Any("payload", payload), zap.Any("urlAResponse", urlAResponse), zap.Any("urlBResponse", urlBResponse))
+		t.failedCH <- NewFailedOutput(payload, errors.New("response is not raw body"))
+		t.statisticsInfo.AddFailed()
+		return
+	}
+
+	diff, urlAOutput, urlBOutput, err := compareBody(t.Config.ResponseFormat, urlABody, urlBBody)
+	if err != nil && statusDiff != "" {
+		// 状态码不同时错误页面的格式可能和正常的响应不同，按照文本对比
+		diff, urlAOutput, urlBOutput, err = compareBody(constant.ResponseFormatText, urlABody, urlBBody)
+	}
+
+	if err != nil {
+		logger.Error(t.ctx, "Task_compareNonJson Failed to compare response", zap.Any("payload", payload), zap.String("responseFormat", t.Config.ResponseFormat), zap.Error(err))
+		t.failedCH <- NewFailedOutput(payload, err)
+		t.statisticsInfo.AddFailed()
+		return
+	}
+
+	if diff == "" && statusDiff == "" && headerDiff == "" {
+		t.outputCh <- t.newSameOutput(payload, urlAResponse, urlBResponse)
+		t.statisticsInfo.AddSame()
+		return
+	}
+
+	output := t.newDiffOutput(payload, urlAResponse, urlBResponse)
+	output.UrlAResponse = urlAOutput
+	output.UrlBResponse = urlBOutput
+	output.Diff = diff
+	output.StatusDiff = statusDiff
+	output.HeaderDiff = headerDiff
+	t.statisticsInfo.AddDiff()
+	t.outputCh <- output
+}
+
+// jsonBodyDiff JSON 格式响应体的对比结果
+type jsonBodyDiff struct {
+	// diff cmp.Diff 的文本结果
+	diff string
+	// differences 结构化的差异
+	differences []util.Difference
+	// arrayDiffs 无序数组的差异
+	arrayDiffs []util.ArrayDiff
+}
+
+// diffJsonBody 对比 JSON 格式的响应体，对比之后恢复原来的数据
+//
+// 对比之前先把忽略的字段设置为 nil，再把无序数组转换为和顺序无关的形式
+func (t *Task) diffJsonBody(urlABody, urlBBody interface{}) (*jsonBodyDiff, error) {
+	for _, field := range t.Config.IgnoreFields {
+		urlANodes, err := util.SetJsonPathToNil(urlABody, field)
+		if err != nil {
+			return nil, errors.New("failed to set field to nil in urlA response: " + err.Error())
+		}
+		defer util.RestoreJsonNodes(urlANodes)
+
+		urlBNodes, err := util.SetJsonPathToNil(urlBBody, field)
+		if err != nil {
+			return nil, errors.New("failed to set field to nil in urlB response: " + err.Error())
+		}
+		defer util.RestoreJsonNodes(urlBNodes)
+	}
+
+	urlAArrays, err := util.NormalizeUnorderedArrays(urlABody, t.UnorderedArrayRules)
+	if err != nil {
+		return nil, errors.New("failed to normalize unordered arrays in urlA response: " + err.Error())
+	}
+	defer urlAArrays.Restore()
+
+	urlBArrays, err := util.NormalizeUnorderedArrays(urlBBody, t.UnorderedArrayRules)
+	if err != nil {
+		return nil, errors.New("failed to normalize unordered arrays in urlB response: " + err.Error())
+	}
+	defer urlBArrays.Restore()
+
+	result := &jsonBodyDiff{}
+	result.diff, result.differences = util.DiffJson(urlABody, urlBBody, t.cmpOptions...)
+	if result.diff != "" {
+		result.arrayDiffs = util.DiffUnorderedArrays(urlAArrays, urlBArrays, t.cmpOptions...)
+	}
+
+	return result, nil
+}
+
+// parseUnorderedArrayRule 解析无序数组规则，格式为 路径:key，key 可以省略
+func parseUnorderedArrayRule(value string) (util.UnorderedArrayRule, error) {
+	rule := util.UnorderedArrayRule{Path: value}
+
+	if index := strings.LastIndex(value, ":"); index >= 0 && !strings.ContainsAny(value[index:], "]'\"") {
+		rule.Path = strings.TrimSpace(value[:index])
+		rule.Key = strings.TrimSpace(value[index+1:])
+	}
+
+	if rule.Path == util.AllArrays {
+		return rule, nil
+	}
+
+	if _, err := util.FindJsonNodes(nil, rule.Path); err != nil {
+		return rule, err
+	}
+
+	return rule, nil
+}
+
+// parsePathTolerance 解析指定路径的数字容差，格式为 路径:绝对容差:相对容差，相对容差可以省略
+//
+// 容差从右往左解析，路径中可以包含冒号，例如 $['a:b']:0.01
+func parsePathTolerance(value string) (util.NumericTolerance, error) {
+	invalidErr := errors.New("invalid float path tolerance format: " + value)
+
+	index := strings.LastIndex(value, ":")
+	if index < 0 {
+		return util.NumericTolerance{}, invalidErr
+	}
+
+	numbers := []string{value[index+1:]}
+	path := value[:index]
+	if index = strings.LastIndex(path, ":"); index >= 0 {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(path[index+1:]), 64); err == nil {
+			numbers = []string{path[index+1:], numbers[0]}
+			path = path[:index]
+		}
+	}
+
+	tolerance := util.NumericTolerance{Path: strings.TrimSpace(path)}
+	if tolerance.Path == "" {
+		return tolerance, invalidErr
+	}
+
+	absolute, err := strconv.ParseFloat(strings.TrimSpace(numbers[0]), 64)
+	if err != nil {
+		return tolerance, invalidErr
+	}
+	tolerance.Absolute = absolute
+
+	if len(numbers) == 2 {
+		relative, err := strconv.ParseFloat(strings.TrimSpace(numbers[1]), 64)
+		if err != nil {
+			return tolerance, invalidErr
+		}
+		tolerance.Relative = relative
+	}
+
+	return tolerance, nil
+}
+
+// compareStatusCode 对比状态码，没有差异时返回空字符串
+func compareStatusCode(urlAResponse, urlBResponse *Response) string {
+	if urlAResponse.StatusCode == urlBResponse.StatusCode {
+		return ""
+	}
+
+	return "- " + strconv.Itoa(urlAResponse.StatusCode) + "\n+ " + strconv.Itoa(urlBResponse.StatusCode) + "\n"
+}
+
+// compareHeaders 按配置的顺序对比响应头，没有差异时返回空字符串
+func compareHeaders(headerKeys []string, urlAResponse, urlBResponse *Response) string {
+	builder := strings.Builder{}
+	for _, key := range headerKeys {
+		canonicalKey := http.CanonicalHeaderKey(key)
+		urlAValues := urlAResponse.Headers[canonicalKey]
+		urlBValues := urlBResponse.Headers[canonicalKey]
+
+		if slices.Equal(urlAValues, urlBValues) {
+			continue
+		}
+
+		for _, value := range urlAValues {
+			builder.WriteString("- " + canonicalKey + ": " + value + "\n")
+		}
+
+		for _, value := range urlBValues {
+			builder.WriteString("+ " + canonicalKey + ": " + value + "\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// compareBody 按响应格式对比响应体，返回差异和需要记录到输出文件中的响应数据
+//
+// text 按行对比，xml 规范化之后按行对比，raw 按字节对比，输出文件中记录 base64 编码后的响应体
+func compareBody(responseFormat string, urlABody, urlBBody []byte) (string, interface{}, interface{}, error) {
+	switch responseFormat {
+	case constant.ResponseFormatText:
+		return util.TextDiff(string(urlABody), string(urlBBody)), string(urlABody), string(urlBBody), nil
+	case constant.ResponseFormatXml:
+		canonicalA, err := util.CanonicalXml(urlABody)
+		if err != nil {
+			return "", nil, nil, errors.New("failed to parse urlA response as xml: " + err.Error())
+		}
+
+		canonicalB, err := util.CanonicalXml(urlBBody)
+		if err != nil {
+			return "", nil, nil, errors.New("failed to parse urlB response as xml: " + err.Error())
+		}
+
+		return util.TextDiff(canonicalA, canonicalB), string(urlABody), string(urlBBody), nil
+	case constant.ResponseFormatRaw:
+		if bytes.Equal(urlABody, urlBBody) {
+			return "", urlABody, urlBBody, nil
+		}
+
+		diff := "- size: " + strconv.Itoa(len(urlABody)) + ", sha256: " + sha256Hex(urlABody) + "\n" +
+			"+ size: " + strconv.Itoa(len(urlBBody)) + ", sha256: " + sha256Hex(urlBBody) + "\n"
+		return diff, urlABody, urlBBody, nil
+	default:
+		return "", nil, nil, errors.New("unsupported response format: " + responseFormat)
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package task
+
+import (
+	nethttp "net/http"
+	"testing"
+
+	"http-diff/constant"
+	"http-diff/lib/http"
+	"http-diff/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareStatusCode(t *testing.T) {
+	tests := []struct {
+		name  string
+		codeA int
+		codeB int
+		diff  string
+	}{
+		{name: "same", codeA: 200, codeB: 200, diff: ""},
+		{name: "different", codeA: 200, codeB: 500, diff: "- 200\n+ 500\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := compareStatusCode(&Response{StatusCode: test.codeA}, &Response{StatusCode: test.codeB})
+			assert.Equal(t, test.diff, diff)
+		})
+	}
+}
+
+func TestCompareHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		headerA nethttp.Header
+		headerB nethttp.Header
+		diff    string
+	}{
+		{
+			name:    "same",
+			keys:    []string{"Content-Type"},
+			headerA: nethttp.Header{"Content-Type": {"application/json"}},
+			headerB: nethttp.Header{"Content-Type": {"application/json"}},
+			diff:    "",
+		},
+		{
+			name:    "case insensitive name",
+			keys:    []string{"content-type"},
+			headerA: nethttp.Header{"Content-Type": {"application/json"}},
+			headerB: nethttp.Header{"Content-Type": {"text/html"}},
+			diff:    "- Content-Type: application/json\n+ Content-Type: text/html\n",
+		},
+		{
+			name:    "missing in b",
+			keys:    []string{"X-Trace-Id"},
+			headerA: nethttp.Header{"X-Trace-Id": {"1"}},
+			headerB: nethttp.Header{},
+			diff:    "- X-Trace-Id: 1\n",
+		},
+		{
+			name:    "missing in a",
+			keys:    []string{"X-Trace-Id"},
+			headerA: nethttp.Header{},
+			headerB: nethttp.Header{"X-Trace-Id": {"1"}},
+			diff:    "+ X-Trace-Id: 1\n",
+		},
+		{
+			name:    "missing in both",
+			keys:    []string{"X-Trace-Id"},
+			headerA: nethttp.Header{},
+			headerB: nethttp.Header{},
+			diff:    "",
+		},
+		{
+			name:    "multiple values in config order",
+			keys:    []string{"Set-Cookie", "Cache-Control"},
+			headerA: nethttp.Header{"Set-Cookie": {"a=1", "b=2"}, "Cache-Control": {"no-cache"}},
+			headerB: nethttp.Header{"Set-Cookie": {"a=1"}, "Cache-Control": {"max-age=60"}},
+			diff:    "- Set-Cookie: a=1\n- Set-Cookie: b=2\n+ Set-Cookie: a=1\n- Cache-Control: no-cache\n+ Cache-Control: max-age=60\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// 响应头先经过 newResponse 按照配置的名字取出，和实际请求时一致
+			info := &Info{ResponseFormat: constant.ResponseFormatText, CompareHeaders: test.keys}
+			urlAResponse, err := newResponse(info, constant.GET, &http.Response{StatusCode: 200, Header: test.headerA})
+			assert.Nil(t, err)
+			urlBResponse, err := newResponse(info, constant.GET, &http.Response{StatusCode: 200, Header: test.headerB})
+			assert.Nil(t, err)
+
+			assert.Equal(t, test.diff, compareHeaders(test.keys, urlAResponse, urlBResponse))
+		})
+	}
+}
+
+func TestParsePathTolerance(t *testing.T) {
+	tests := []struct {
+		value     string
+		tolerance util.NumericTolerance
+		hasErr    bool
+	}{
+		{value: "data.price:0.01", tolerance: util.NumericTolerance{Path: "data.price", Absolute: 0.01}},
+		{value: "data.items[*].score:0:0.0001", tolerance: util.NumericTolerance{Path: "data.items[*].score", Relative: 0.0001}},
+		{value: " data.price : 0.5 : 0.1 ", tolerance: util.NumericTolerance{Path: "data.price", Absolute: 0.5, Relative: 0.1}},
+		{value: "$['a:b']:0.01", tolerance: util.NumericTolerance{Path: "$['a:b']", Absolute: 0.01}},
+		{value: "$['a:b']:0.01:0.2", tolerance: util.NumericTolerance{Path: "$['a:b']", Absolute: 0.01, Relative: 0.2}},
+		{value: "$['a:1']:0.01", tolerance: util.NumericTolerance{Path: "$['a:1']", Absolute: 0.01}},
+		{value: "data.price", hasErr: true},
+		{value: ":0.01", hasErr: true},
+		{value: "data.price:abc", hasErr: true},
+		{value: "data.price:0.01:abc", hasErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			tolerance, err := parsePathTolerance(test.value)
+			if test.hasErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.tolerance, tolerance)
+		})
+	}
+}
+
+func TestParseUnorderedArrayRule(t *testing.T) {
+	tests := []struct {
+		value  string
+		rule   util.UnorderedArrayRule
+		hasErr bool
+	}{
+		{value: "data.items:id", rule: util.UnorderedArrayRule{Path: "data.items", Key: "id"}},
+		{value: "data.tags", rule: util.UnorderedArrayRule{Path: "data.tags"}},
+		{value: "*", rule: util.UnorderedArrayRule{Path: util.AllArrays}},
+		{value: "$['a:b']", rule: util.UnorderedArrayRule{Path: "$['a:b']"}},
+		{value: "$['a:b']:id", rule: util.UnorderedArrayRule{Path: "$['a:b']", Key: "id"}},
+		{value: "$['a:b'].items:id", rule: util.UnorderedArrayRule{Path: "$['a:b'].items", Key: "id"}},
+		{value: "data[:id", hasErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			rule, err := parseUnorderedArrayRule(test.value)
+			if test.hasErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.rule, rule)
+		})
+	}
+}
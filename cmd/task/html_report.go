@@ -0,0 +1,522 @@
+package task
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+)
+
+const (
+	// DefaultHtmlReportLimit HTML 报告中默认最多展示的差异和错误数量
+	DefaultHtmlReportLimit = 1000
+
+	// htmlNodeContainsDiff 节点的子节点有差异
+	htmlNodeContainsDiff = 1
+	// htmlNodeDiff 节点本身有差异
+	htmlNodeDiff = 2
+)
+
+// HtmlReportConfig HTML 报告配置
+type HtmlReportConfig struct {
+	// TaskName 任务名称
+	TaskName string
+	// WorkDir 工作目录，从该目录读取对比结果和错误信息
+	WorkDir string
+	// HtmlFile 报告文件路径，为空时写入工作目录的 {任务名}_report.html 文件
+	HtmlFile string
+	// Limit 最多展示的差异和错误数量，小于等于 0 时使用默认值
+	Limit int
+}
+
+// htmlReportData HTML 报告模板数据
+type htmlReportData struct {
+	Report      *DiffReport
+	GeneratedAt string
+	OutputFile  string
+	FailedFile  string
+
+	// ReportFile 任务结束时生成的差异汇总报告，不存在时根据结果文件重新统计数量
+	ReportFile string
+
+	Rows            []*htmlReportRow
+	RowsTruncated   bool
+	Failed          []*FailedOutPut
+	FailedTruncated bool
+}
+
+// htmlReportRow 一条有差异的对比结果
+type htmlReportRow struct {
+	LineNumber  int
+	Payload     *Payload
+	Diff        string
+	StatusDiff  string
+	HeaderDiff  string
+	Differences []util.Difference
+	ArrayDiffs  []util.ArrayDiff
+
+	LatencyA        float64
+	LatencyB        float64
+	PerformanceDiff bool
+
+	UrlAResponse template.HTML
+	UrlBResponse template.HTML
+}
+
+// GenerateHtmlReport 读取任务的对比结果和错误信息，生成静态 HTML 报告，返回报告文件路径
+func GenerateHtmlReport(config HtmlReportConfig) (string, error) {
+	if config.TaskName == "" {
+		return "", errors.New("task name cannot be empty")
+	}
+
+	if config.Limit <= 0 {
+		config.Limit = DefaultHtmlReportLimit
+	}
+
+	if config.HtmlFile == "" {
+		config.HtmlFile = path.Join(config.WorkDir, config.TaskName+"_report.html")
+	}
+
+	data := &htmlReportData{
+		GeneratedAt: time.Now().Format(time.DateTime),
+		OutputFile:  findResultFile(path.Join(config.WorkDir, config.TaskName+"_output.txt")),
+		FailedFile:  findResultFile(path.Join(config.WorkDir, config.TaskName+"_failed_payload.txt")),
+	}
+
+	report, err := readDiffReport(path.Join(config.WorkDir, config.TaskName+"_report.json"))
+	if err != nil {
+		return "", err
+	}
+	if report != nil {
+		data.ReportFile = path.Join(config.WorkDir, config.TaskName+"_report.json")
+	}
+
+	collector := newReportCollector()
+	statisticsInfo := NewStatisticsInfo()
+
+	err = scanReportFile(data.OutputFile, func(lineNumber int, line []byte) error {
+		output := &OutPut{}
+		if err := sonic.Unmarshal(line, output); err != nil {
+			return errors.New("failed to unmarshal output line " + strconv.Itoa(lineNumber) + ": " + err.Error())
+		}
+
+		collector.Add(output)
+		if output.PerformanceDiff {
+			statisticsInfo.AddPerformanceDiff()
+		}
+
+		if !output.HasDiff() {
+			statisticsInfo.AddSame()
+		} else {
+			statisticsInfo.AddDiff()
+		}
+
+		if !output.HasDiff() && !output.PerformanceDiff {
+			return nil
+		}
+
+		if len(data.Rows) >= config.Limit {
+			data.RowsTruncated = true
+			return nil
+		}
+
+		data.Rows = append(data.Rows, newHtmlReportRow(lineNumber, output))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = scanReportFile(data.FailedFile, func(lineNumber int, line []byte) error {
+		statisticsInfo.AddFailed()
+
+		if len(data.Failed) >= config.Limit {
+			data.FailedTruncated = true
+			return nil
+		}
+
+		failedOutput := &FailedOutPut{}
+		if err := sonic.Unmarshal(line, failedOutput); err != nil {
+			return errors.New("failed to unmarshal failed payload line " + strconv.Itoa(lineNumber) + ": " + err.Error())
+		}
+
+		data.Failed = append(data.Failed, failedOutput)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// 默认不输出没有差异的结果，优先使用任务结束时统计的数量
+	data.Report = report
+	if data.Report == nil {
+		data.Report = collector.Report(config.TaskName, statisticsInfo)
+		data.Report.TotalCount = statisticsInfo.GetProcessedCount()
+	}
+
+	file, err := os.Create(config.HtmlFile)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	writer := bufio.NewWriter(file)
+	if err = htmlReportTemplate.Execute(writer, data); err != nil {
+		return "", err
+	}
+
+	if err = writer.Flush(); err != nil {
+		return "", err
+	}
+
+	return config.HtmlFile, nil
+}
+
+// readDiffReport 读取差异汇总报告，文件不存在时返回 nil
+func readDiffReport(filePath string) (*DiffReport, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	report := &DiffReport{}
+	if err := sonic.Unmarshal(content, report); err != nil {
+		return nil, errors.New("failed to unmarshal report file " + filePath + ": " + err.Error())
+	}
+
+	return report, nil
+}
+
+// findResultFile 结果文件不存在时查找压缩后的文件，都不存在时返回原路径
+func findResultFile(filePath string) string {
+	for _, compression := range []string{"", util.CompressionGzip, util.CompressionZstd} {
+		candidate := filePath + util.CompressionExtension(compression)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return filePath
+}
+
+// scanReportFile 逐行读取结果文件，文件不存在时当作空文件处理，压缩文件自动解压
+func scanReportFile(filePath string, handle func(lineNumber int, line []byte) error) error {
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// 对比结果中包含两个接口的响应数据，单行最大长度是响应体最大长度的两倍
+	maxLineSize := 32 * 1024 * 1024
+	buffer := make([]byte, 0, 1024*1024)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(buffer, maxLineSize)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		if err = handle(lineNumber, line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func newHtmlReportRow(lineNumber int, output *OutPut) *htmlReportRow {
+	marks := newJsonTreeMarks(output)
+
+	return &htmlReportRow{
+		LineNumber:  lineNumber,
+		Payload:     output.Payload,
+		Diff:        output.Diff,
+		StatusDiff:  output.StatusDiff,
+		HeaderDiff:  output.HeaderDiff,
+		Differences: output.Differences,
+		ArrayDiffs:  output.ArrayDiffs,
+
+		LatencyA:        output.LatencyA,
+		LatencyB:        output.LatencyB,
+		PerformanceDiff: output.PerformanceDiff,
+		UrlAResponse:    renderJsonTree(output.UrlAResponse, marks),
+		UrlBResponse:    renderJsonTree(output.UrlBResponse, marks),
+	}
+}
+
+// jsonTreeMarks 响应数据中需要高亮的节点
+type jsonTreeMarks struct {
+	// nodes 有差异的节点和它们的上级节点，key 为差异中的路径
+	nodes map[string]int
+	// keyFields 按 key 对比的无序数组的路径和 key 字段，差异路径中这些数组的元素为 [key=值] 的形式
+	keyFields map[string]string
+}
+
+// newJsonTreeMarks 标记有差异的节点和它们的上级节点，上级节点在页面中默认展开
+func newJsonTreeMarks(output *OutPut) *jsonTreeMarks {
+	marks := &jsonTreeMarks{nodes: make(map[string]int), keyFields: make(map[string]string)}
+	for _, difference := range output.Differences {
+		for _, ancestor := range util.JsonPathAncestors(difference.Path) {
+			if marks.nodes[ancestor] == 0 {
+				marks.nodes[ancestor] = htmlNodeContainsDiff
+			}
+		}
+		marks.nodes[difference.Path] = htmlNodeDiff
+
+		for arrayPath, keyField := range keyedArrayFields(difference.Path) {
+			marks.keyFields[arrayPath] = keyField
+		}
+	}
+
+	// 没有结构化差异时（非 JSON 格式）整个响应体都标记为有差异
+	if output.Diff != "" && len(output.Differences) == 0 {
+		marks.nodes["$"] = htmlNodeDiff
+	}
+
+	return marks
+}
+
+// keyedArrayFields 找出路径中 [key=值] 形式的数组元素，返回数组的路径和 key 字段
+//
+// 例如 $.items[id=3].tags[0] 返回 $.items 和 id
+func keyedArrayFields(jsonPath string) map[string]string {
+	result := make(map[string]string)
+	inQuote := false
+	for i := 0; i < len(jsonPath); i++ {
+		switch jsonPath[i] {
+		case '\'':
+			inQuote = !inQuote
+		case '[':
+			if inQuote {
+				continue
+			}
+
+			end := strings.Index(jsonPath[i:], "]")
+			if end < 0 {
+				return result
+			}
+
+			content := jsonPath[i+1 : i+end]
+			if keyField, _, ok := strings.Cut(content, "="); ok && !strings.HasPrefix(content, "'") {
+				result[jsonPath[:i]] = keyField
+			}
+		}
+	}
+
+	return result
+}
+
+// childPath 数组元素的路径，按 key 对比的无序数组的元素使用和差异相同的 [key=值] 形式
+func (m *jsonTreeMarks) childPath(arrayPath string, index int, item interface{}) string {
+	keyField, ok := m.keyFields[arrayPath]
+	if !ok {
+		return util.ChildIndexPath(arrayPath, index)
+	}
+
+	element, ok := item.(map[string]interface{})
+	if !ok {
+		return util.ChildIndexPath(arrayPath, index)
+	}
+
+	keyValue, ok := element[keyField]
+	if !ok {
+		return util.ChildIndexPath(arrayPath, index)
+	}
+
+	return arrayPath + "[" + keyField + "=" + fmt.Sprint(keyValue) + "]"
+}
+
+// renderJsonTree 把响应数据渲染为可折叠的树，有差异的节点会被高亮
+func renderJsonTree(value interface{}, marks *jsonTreeMarks) template.HTML {
+	builder := &strings.Builder{}
+	renderJsonNode(builder, "$", "$", value, marks)
+	return template.HTML(builder.String())
+}
+
+func renderJsonNode(builder *strings.Builder, label string, jsonPath string, value interface{}, marks *jsonTreeMarks) {
+	class := "node"
+	switch marks.nodes[jsonPath] {
+	case htmlNodeDiff:
+		class += " diff"
+	case htmlNodeContainsDiff:
+		class += " contains"
+	}
+
+	open := ""
+	if marks.nodes[jsonPath] != 0 {
+		open = " open"
+	}
+
+	title := ` title="` + html.EscapeString(jsonPath) + `"`
+	labelHtml := `<span class="key">` + html.EscapeString(label) + `</span>: `
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		builder.WriteString(`<details class="` + class + `"` + title + open + `><summary>` + labelHtml + `{` + strconv.Itoa(len(keys)) + `}</summary>`)
+		for _, key := range keys {
+			renderJsonNode(builder, key, util.ChildKeyPath(jsonPath, key), typedValue[key], marks)
+		}
+		builder.WriteString(`</details>`)
+	case []interface{}:
+		builder.WriteString(`<details class="` + class + `"` + title + open + `><summary>` + labelHtml + `[` + strconv.Itoa(len(typedValue)) + `]</summary>`)
+		for index, item := range typedValue {
+			renderJsonNode(builder, strconv.Itoa(index), marks.childPath(jsonPath, index, item), item, marks)
+		}
+		builder.WriteString(`</details>`)
+	case string:
+		// 非 JSON 格式的响应体是多行文本，使用 pre 展示
+		if strings.Contains(typedValue, "\n") {
+			builder.WriteString(`<div class="` + class + `"` + title + `>` + labelHtml + `<pre>` + html.EscapeString(typedValue) + `</pre></div>`)
+			return
+		}
+		builder.WriteString(`<div class="` + class + `"` + title + `>` + labelHtml + `<span class="value">` + html.EscapeString(strconv.Quote(typedValue)) + `</span></div>`)
+	default:
+		marshal, _ := sonic.MarshalString(typedValue)
+		builder.WriteString(`<div class="` + class + `"` + title + `>` + labelHtml + `<span class="value">` + html.EscapeString(marshal) + `</span></div>`)
+	}
+}
+
+// toJson 模板中把数据序列化为 JSON 字符串
+func toJson(value interface{}) string {
+	marshal, err := sonic.MarshalString(value)
+	if err != nil {
+		return err.Error()
+	}
+
+	return marshal
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"toJson": toJson,
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<title>{{.Report.TaskName}} 对比报告</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; margin: 24px; color: #24292f; }
+h1, h2 { font-weight: 600; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 16px; }
+th, td { border: 1px solid #d0d7de; padding: 6px 10px; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+pre, code, .node { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 12px; }
+pre { white-space: pre-wrap; word-break: break-all; margin: 0; }
+.summary td:first-child { width: 200px; font-weight: 600; }
+.row { border: 1px solid #d0d7de; border-radius: 6px; margin-bottom: 12px; padding: 8px 12px; }
+.row > summary { cursor: pointer; }
+.side-by-side { display: flex; gap: 12px; margin-top: 8px; }
+.side-by-side > div { flex: 1; min-width: 0; overflow-x: auto; border: 1px solid #d0d7de; padding: 8px; }
+.node { margin-left: 16px; }
+.node > summary { cursor: pointer; margin-left: -16px; }
+.key { color: #0550ae; }
+.value { color: #116329; }
+.contains > summary { background: #fff8c5; }
+.diff, .diff > summary { background: #ffebe9; }
+.muted { color: #57606a; }
+</style>
+</head>
+<body>
+<h1>{{.Report.TaskName}} 对比报告</h1>
+<p class="muted">生成时间：{{.GeneratedAt}}，对比结果文件：{{.OutputFile}}，错误信息文件：{{.FailedFile}}</p>
+
+<h2>汇总</h2>
+<table class="summary">
+<tr><td>已处理数量</td><td>{{.Report.TotalCount}}</td></tr>
+<tr><td>有差异数量</td><td>{{.Report.DiffCount}}</td></tr>
+<tr><td>无差异数量</td><td>{{.Report.SameCount}}</td></tr>
+<tr><td>失败数量</td><td>{{.Report.FailedCount}}</td></tr>
+<tr><td>性能差异数量</td><td>{{.Report.PerformanceDiffCount}}</td></tr>
+</table>
+{{if .ReportFile}}<p class="muted">数量来自任务结束时生成的汇总报告：{{.ReportFile}}</p>{{else}}<p class="muted">没有找到任务的汇总报告，数量根据结果文件统计，无差异数量只在开启 output_show_no_diff_line 时统计。</p>{{end}}
+
+<h2>按路径统计差异</h2>
+{{if .Report.Paths}}
+<table>
+<tr><th>路径</th><th>数量</th><th>占比</th><th>差异类型</th><th>示例参数</th></tr>
+{{range .Report.Paths}}
+<tr>
+<td><code>{{.Path}}</code></td>
+<td>{{.Count}}</td>
+<td>{{.Percentage}}</td>
+<td>{{range $kind, $count := .Kinds}}{{$kind}}: {{$count}}<br>{{end}}</td>
+<td>{{range .Samples}}<pre>{{toJson .}}</pre>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>没有差异。</p>
+{{end}}
+
+<h2>差异详情</h2>
+{{if .RowsTruncated}}<p class="muted">只展示前 {{len .Rows}} 条差异。</p>{{end}}
+{{range .Rows}}
+<details class="row">
+<summary>第 {{.LineNumber}} 行 <code>{{toJson .Payload}}</code> <span class="muted">{{len .Differences}} 处差异，耗时 A: {{.LatencyA}}ms B: {{.LatencyB}}ms</span>{{if .PerformanceDiff}} <strong>性能差异</strong>{{end}}</summary>
+{{if .StatusDiff}}<p>状态码差异：</p><pre>{{.StatusDiff}}</pre>{{end}}
+{{if .HeaderDiff}}<p>响应头差异：</p><pre>{{.HeaderDiff}}</pre>{{end}}
+{{if .Differences}}
+<table>
+<tr><th>路径</th><th>类型</th><th>A</th><th>B</th></tr>
+{{range .Differences}}<tr><td><code>{{.Path}}</code></td><td>{{.Kind}}</td><td><pre>{{toJson .ValueA}}</pre></td><td><pre>{{toJson .ValueB}}</pre></td></tr>
+{{end}}
+</table>
+{{else if .Diff}}
+<details><summary>对比结果</summary><pre>{{.Diff}}</pre></details>
+{{end}}
+{{if .ArrayDiffs}}<details><summary>无序数组差异</summary><pre>{{toJson .ArrayDiffs}}</pre></details>{{end}}
+<div class="side-by-side">
+<div><strong>urlAResponse</strong>{{.UrlAResponse}}</div>
+<div><strong>urlBResponse</strong>{{.UrlBResponse}}</div>
+</div>
+</details>
+{{end}}
+
+<h2>失败请求</h2>
+{{if .FailedTruncated}}<p class="muted">只展示前 {{len .Failed}} 条失败请求。</p>{{end}}
+{{if .Failed}}
+<table>
+<tr><th>请求参数</th><th>错误信息</th></tr>
+{{range .Failed}}<tr><td><pre>{{toJson .}}</pre></td><td><pre>{{.Err}}</pre></td></tr>
+{{end}}
+</table>
+{{else}}
+<p>没有失败的请求。</p>
+{{end}}
+</body>
+</html>
+`))
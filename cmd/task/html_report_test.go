@@ -0,0 +1,123 @@
+package task
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+
+	"http-diff/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedArrayFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		jsonPath string
+		want     map[string]string
+	}{
+		{name: "no array", jsonPath: "$.a.b", want: map[string]string{}},
+		{name: "index only", jsonPath: "$.items[0].tags[1]", want: map[string]string{}},
+		{name: "keyed", jsonPath: "$.items[id=3].tags[0]", want: map[string]string{"$.items": "id"}},
+		{name: "nested keyed", jsonPath: "$.items[id=3].skus[sku=a]", want: map[string]string{"$.items": "id", "$.items[id=3].skus": "sku"}},
+		{name: "quoted key", jsonPath: "$['a=b'][0]", want: map[string]string{}},
+		{name: "bracket in quoted key", jsonPath: "$['a[id=1]'].items[id=2]", want: map[string]string{"$['a[id=1]'].items": "id"}},
+		{name: "unclosed", jsonPath: "$.items[id=3", want: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, keyedArrayFields(tt.jsonPath))
+		})
+	}
+}
+
+func TestRenderJsonTreeEscape(t *testing.T) {
+	output := &OutPut{
+		Diff:        "-",
+		Differences: []util.Difference{{Path: "$.<b>", Kind: "changed"}},
+	}
+
+	value := map[string]interface{}{
+		"<b>":  "<script>alert(1)</script>",
+		"text": "a\n<i>b</i>",
+	}
+
+	rendered := string(renderJsonTree(value, newJsonTreeMarks(output)))
+	assert.NotContains(t, rendered, "<script>")
+	assert.NotContains(t, rendered, "<b>")
+	assert.NotContains(t, rendered, "<i>")
+	assert.Contains(t, rendered, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.Contains(t, rendered, `<span class="key">&lt;b&gt;</span>`)
+	assert.Contains(t, rendered, "<pre>a\n&lt;i&gt;b&lt;/i&gt;</pre>")
+
+	// 有差异的节点和上级节点被高亮并展开
+	assert.Contains(t, rendered, `<details class="node contains" title="$" open>`)
+	assert.Contains(t, rendered, `<div class="node diff" title="$.&lt;b&gt;">`)
+}
+
+func TestGenerateHtmlReport(t *testing.T) {
+	workDir := t.TempDir()
+
+	outputLines := make([]string, 0)
+	for i := 1; i <= 5; i++ {
+		outputLines = append(outputLines, `{"payload":{"params":{"id":"`+strconv.Itoa(i)+`"},"headers":"","body":""},"urlAResponse":{"id":`+strconv.Itoa(i)+`},"urlBResponse":{"id":0},"diff":"-","differences":[{"path":"$.id","kind":"changed","valueA":`+strconv.Itoa(i)+`,"valueB":0}],"latencyA":1,"latencyB":1}`)
+	}
+	outputLines = append(outputLines, `{"payload":{"params":{"id":"6"},"headers":"","body":""},"urlAResponse":{"id":6},"urlBResponse":{"id":6},"diff":"","latencyA":1,"latencyB":1}`)
+	writeTestFile(t, workDir, "test_output.txt", outputLines...)
+	writeTestFile(t, workDir, "test_failed_payload.txt",
+		`{"params":{"id":"7"},"headers":"","body":"","err":"timeout 7"}`,
+		`{"params":{"id":"8"},"headers":"","body":"","err":"timeout 8"}`,
+		`{"params":{"id":"9"},"headers":"","body":"","err":"timeout 9"}`,
+	)
+
+	htmlFile, err := GenerateHtmlReport(HtmlReportConfig{TaskName: "test", WorkDir: workDir, Limit: 2})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, path.Join(workDir, "test_report.html"), htmlFile)
+
+	content, err := os.ReadFile(htmlFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	report := string(content)
+
+	// 没有汇总报告时根据结果文件统计数量
+	assert.Contains(t, report, "<tr><td>已处理数量</td><td>9</td></tr>")
+	assert.Contains(t, report, "<tr><td>有差异数量</td><td>5</td></tr>")
+	assert.Contains(t, report, "<tr><td>失败数量</td><td>3</td></tr>")
+	assert.Contains(t, report, "没有找到任务的汇总报告")
+
+	// 差异和失败请求都只展示前 2 条
+	assert.Equal(t, 2, strings.Count(report, `<details class="row">`))
+	assert.Contains(t, report, "只展示前 2 条差异。")
+	assert.Contains(t, report, "第 1 行")
+	assert.Contains(t, report, "第 2 行")
+	assert.NotContains(t, report, "第 3 行")
+	assert.Contains(t, report, "只展示前 2 条失败请求。")
+	assert.Contains(t, report, "timeout 8")
+	assert.NotContains(t, report, "timeout 9")
+
+	// 存在汇总报告时优先使用其中的数量
+	writeTestFile(t, workDir, "test_report.json", `{"taskName":"test","totalCount":100,"sameCount":60,"diffCount":30,"failedCount":10,"paths":[]}`)
+	htmlFile, err = GenerateHtmlReport(HtmlReportConfig{TaskName: "test", WorkDir: workDir, HtmlFile: path.Join(workDir, "custom.html")})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, path.Join(workDir, "custom.html"), htmlFile)
+
+	content, err = os.ReadFile(htmlFile)
+	if !assert.Nil(t, err) {
+		return
+	}
+	report = string(content)
+
+	assert.Contains(t, report, "<tr><td>已处理数量</td><td>100</td></tr>")
+	assert.Contains(t, report, "<tr><td>有差异数量</td><td>30</td></tr>")
+	assert.Contains(t, report, "数量来自任务结束时生成的汇总报告")
+	assert.Equal(t, 5, strings.Count(report, `<details class="row">`))
+	assert.NotContains(t, report, "只展示前")
+}
@@ -1,8 +1,18 @@
 package task
 
+import (
+	"http-diff/lib/rewrite"
+)
+
 // Info 任务信息
 type Info struct {
-	Method      string `json:"method"`      //请求方法 GET、POST
-	Url         string `json:"url"`         // 请求地址
-	ContentType string `json:"contentType"` // 请求内容类型
+	Method         string `json:"method"`         //请求方法 GET、POST、PUT、PATCH、DELETE、HEAD
+	Url            string `json:"url"`            // 请求地址
+	ContentType    string `json:"contentType"`    // 请求内容类型
+	ResponseFormat string `json:"responseFormat"` // 响应数据格式 json、text、xml、raw
+
+	CompareStatusCode bool     `json:"compareStatusCode"` // 是否对比状态码
+	CompareHeaders    []string `json:"compareHeaders"`    // 需要对比的响应头
+
+	Rewrite *rewrite.Rules `json:"-"` // 发送请求之前的改写规则，为 nil 时不改写
 }
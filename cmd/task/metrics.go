@@ -0,0 +1,84 @@
+package task
+
+import (
+	"strconv"
+	"time"
+
+	"http-diff/lib/metrics"
+	"http-diff/lib/ratelimit"
+)
+
+// 请求的接口，作为指标的 side 标签
+const (
+	sideA = "a"
+	sideB = "b"
+)
+
+// statusCodeError 请求失败时指标的 code 标签
+const statusCodeError = "error"
+
+// limiterTask 任务限流器的 limiter 标签，接口的限流器使用 sideA 和 sideB
+const limiterTask = "task"
+
+var (
+	payloadTotalMetric = metrics.Default.NewGaugeFuncVec("http_diff_task_payload_total",
+		"Total number of payload lines of the task.", "task")
+	resultTotalMetric = metrics.Default.NewCounterFuncVec("http_diff_task_results_total",
+		"Number of processed payloads by result (same, diff, failed, recorded).", "task", "result")
+	performanceDiffTotalMetric = metrics.Default.NewCounterFuncVec("http_diff_task_performance_diff_total",
+		"Number of payloads whose url_b latency exceeds url_a latency multiplied by latency_ratio.", "task")
+	concurrencyMetric = metrics.Default.NewGaugeFuncVec("http_diff_task_concurrency",
+		"Current concurrency of the task.", "task")
+	rateLimitQPSMetric = metrics.Default.NewGaugeFuncVec("http_diff_rate_limit_qps",
+		"Effective qps of the rate limiter, 0 means unlimited.", "task", "limiter")
+	rateLimitBurstMetric = metrics.Default.NewGaugeFuncVec("http_diff_rate_limit_burst",
+		"Effective burst of the rate limiter, 0 means unlimited.", "task", "limiter")
+	inFlightRequestsMetric = metrics.Default.NewGaugeVec("http_diff_requests_in_flight",
+		"Number of requests currently in flight.", "task", "side")
+	requestDurationMetric = metrics.Default.NewHistogramVec("http_diff_request_duration_seconds",
+		"Request latency in seconds by side and status code.", metrics.DefaultBuckets, "task", "side", "code")
+)
+
+// registerMetrics 注册任务的统计指标，计数类的指标在输出时从统计信息中读取
+func (t *Task) registerMetrics() {
+	name := t.Config.TaskName
+	statisticsInfo := t.statisticsInfo
+
+	payloadTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetTotalCount()) }, name)
+	resultTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetSameCount()) }, name, "same")
+	resultTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetDiffCount()) }, name, "diff")
+	resultTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetFailedCount()) }, name, "failed")
+	resultTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetRecordedCount()) }, name, "recorded")
+	performanceDiffTotalMetric.Set(func() float64 { return float64(statisticsInfo.GetPerformanceDiffCount()) }, name)
+	concurrencyMetric.Set(func() float64 { return float64(t.currentConcurrency()) }, name)
+
+	t.registerLimiterMetrics(limiterTask, t.limiter)
+	t.registerLimiterMetrics(sideA, t.urlALimiter)
+	t.registerLimiterMetrics(sideB, t.urlBLimiter)
+}
+
+// registerLimiterMetrics 注册限流器生效的 qps 和 burst，burst 小于 1 时按 1 处理，不限流时都为 0
+func (t *Task) registerLimiterMetrics(label string, limiter *ratelimit.Limiter) {
+	rateLimitQPSMetric.Set(limiter.QPS, t.Config.TaskName, label)
+	rateLimitBurstMetric.Set(func() float64 { return float64(limiter.Burst()) }, t.Config.TaskName, label)
+}
+
+// observeRequest 记录请求中的数量，请求完成之后记录耗时
+func (t *Task) observeRequest(side string, request func() (*Response, error)) (*Response, error) {
+	inFlight := inFlightRequestsMetric.WithLabelValues(t.Config.TaskName, side)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	response, err := request()
+	latency := time.Since(start)
+
+	code := statusCodeError
+	if err == nil && response != nil {
+		code = strconv.Itoa(response.StatusCode)
+	}
+	requestDurationMetric.WithLabelValues(t.Config.TaskName, side, code).Observe(latency.Seconds())
+	t.adaptiveLimiter.Observe(latency, err != nil)
+
+	return response, err
+}
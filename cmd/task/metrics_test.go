@@ -0,0 +1,35 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	"http-diff/lib/metrics"
+	"http-diff/lib/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterLimiterMetrics(t *testing.T) {
+	task := &Task{
+		Config:      Config{TaskName: "limiter_metrics"},
+		limiter:     ratelimit.NewLimiter(5, 0),
+		urlALimiter: ratelimit.NewLimiter(2.5, 3),
+	}
+	task.registerLimiterMetrics(limiterTask, task.limiter)
+	task.registerLimiterMetrics(sideA, task.urlALimiter)
+	task.registerLimiterMetrics(sideB, task.urlBLimiter)
+
+	builder := &strings.Builder{}
+	_, err := metrics.Default.WriteTo(builder)
+	assert.Nil(t, err)
+
+	output := builder.String()
+	assert.Contains(t, output, `http_diff_rate_limit_qps{task="limiter_metrics",limiter="task"} 5`)
+	assert.Contains(t, output, `http_diff_rate_limit_qps{task="limiter_metrics",limiter="a"} 2.5`)
+	assert.Contains(t, output, `http_diff_rate_limit_qps{task="limiter_metrics",limiter="b"} 0`)
+	// burst 小于 1 时按 1 处理
+	assert.Contains(t, output, `http_diff_rate_limit_burst{task="limiter_metrics",limiter="task"} 1`)
+	assert.Contains(t, output, `http_diff_rate_limit_burst{task="limiter_metrics",limiter="a"} 3`)
+	assert.Contains(t, output, `http_diff_rate_limit_burst{task="limiter_metrics",limiter="b"} 0`)
+}
@@ -1,20 +1,51 @@
 package task
 
+import (
+	"http-diff/util"
+)
+
 type OutPut struct {
 	Payload *Payload `json:"payload"` // 请求负载
 
-	UrlAResponse interface{} `json:"urlAResponse"` // urlA 响应
-	UrlBResponse interface{} `json:"urlBResponse"` // urlB 响应
+	UrlAResponse interface{} `json:"urlAResponse"` // urlA 响应，text 和 xml 格式为字符串，raw 格式为 base64 编码的响应体
+	UrlBResponse interface{} `json:"urlBResponse"` // urlB 响应，text 和 xml 格式为字符串，raw 格式为 base64 编码的响应体
+
+	UrlAStatusCode int `json:"urlAStatusCode,omitempty"` // urlA 状态码，开启状态码对比时记录
+	UrlBStatusCode int `json:"urlBStatusCode,omitempty"` // urlB 状态码，开启状态码对比时记录
+
+	UrlAHeaders map[string][]string `json:"urlAHeaders,omitempty"` // urlA 需要对比的响应头
+	UrlBHeaders map[string][]string `json:"urlBHeaders,omitempty"` // urlB 需要对比的响应头
+
+	Diff       string `json:"diff"`                 //响应对比结果，cmp.Diff 的文本，不同版本的格式可能不同
+	StatusDiff string `json:"statusDiff,omitempty"` // 状态码对比结果
+	HeaderDiff string `json:"headerDiff,omitempty"` // 响应头对比结果
 
-	Diff string `json:"diff"` //响应对比结果
+	Differences []util.Difference `json:"differences,omitempty"` // 结构化的响应体差异，只在响应格式为 json 时记录
+	ArrayDiffs  []util.ArrayDiff  `json:"arrayDiffs,omitempty"`  // 无序数组中新增、删除和修改的元素
+
+	LatencyA        float64 `json:"latencyA"`                  // urlA 请求耗时，单位毫秒
+	LatencyB        float64 `json:"latencyB"`                  // urlB 请求耗时，单位毫秒
+	PerformanceDiff bool    `json:"performanceDiff,omitempty"` // urlB 的耗时超过 urlA 的耗时乘以 latency_ratio
+}
+
+// HasDiff 响应体、状态码或响应头是否有差异
+func (o *OutPut) HasDiff() bool {
+	return o.Diff != "" || o.StatusDiff != "" || o.HeaderDiff != ""
 }
 
 // FailedOutPut 出错时的信息
+//
+// 请求参数的格式和输入保持一致，可以当作输入复用
 type FailedOutPut struct {
-	Params  string `json:"params"`
-	Headers string `json:"headers"`
-	Body    string `json:"body"`
-	Err     string `json:"err"`
+	Params  PayloadValue `json:"params"`
+	Headers PayloadValue `json:"headers"`
+	Body    PayloadValue `json:"body"`
+	Method  string       `json:"method,omitempty"`
+	Path    string       `json:"path,omitempty"`
+	Err     string       `json:"err"`
+
+	// payload 出错的请求参数，用于记录断点
+	payload *Payload
 }
 
 func NewFailedOutput(payload *Payload, err error) *FailedOutPut {
@@ -27,6 +58,9 @@ func NewFailedOutput(payload *Payload, err error) *FailedOutPut {
 		Params:  payload.Params,
 		Headers: payload.Headers,
 		Body:    payload.Body,
+		Method:  payload.Method,
+		Path:    payload.Path,
 		Err:     errStr,
+		payload: payload,
 	}
 }
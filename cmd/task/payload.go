@@ -1,7 +1,283 @@
 package task
 
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"http-diff/constant"
+	"http-diff/lib/variable"
+
+	"github.com/bytedance/sonic"
+)
+
 type Payload struct {
-	Params  string `json:"params"`
-	Headers string `json:"headers"`
-	Body    string `json:"body"`
+	// Params URL 参数，URL 编码之后的字符串，或者 JSON 对象，值为数组时表示同名的多个参数
+	Params PayloadValue `json:"params"`
+	// Headers 请求头，转义之后的 JSON 字符串，或者 JSON 对象
+	Headers PayloadValue `json:"headers"`
+	// Body 请求体，转义之后的字符串，或者 JSON 对象和数组，表单请求的 JSON 对象会被转换为表单参数
+	Body PayloadValue `json:"body"`
+	// Method 请求方法，不为空时覆盖任务配置的请求方法
+	Method string `json:"method,omitempty"`
+	// Path 请求路径，不为空时拼接在 url_a 和 url_b 后面
+	Path string `json:"path,omitempty"`
+
+	// fileIndex 请求参数所在文件的下标，用于记录断点
+	fileIndex int
+	// lineNumber 请求参数所在的行号，用于记录断点
+	lineNumber int
+	// template 请求参数模板，不为 nil 时在请求之前替换其中的变量
+	template *variable.Template
+	// values 迭代变量的值
+	values map[string]string
+}
+
+// PayloadValue 请求参数中的 params、headers 和 body，可以是转义之后的字符串，也可以是原生的 JSON 对象或数组
+//
+// 序列化时保持读取时的格式，原生 JSON 会被压缩为一行
+type PayloadValue struct {
+	// text 字符串格式的值
+	text string
+	// raw 原生 JSON 格式的值，不为空时忽略 text
+	raw []byte
+}
+
+// StringValue 字符串格式的值
+func StringValue(text string) PayloadValue {
+	return PayloadValue{text: text}
+}
+
+// JsonValue 把数据序列化为原生 JSON 格式的值
+func JsonValue(value interface{}) (PayloadValue, error) {
+	marshal, err := sonic.Marshal(value)
+	if err != nil {
+		return PayloadValue{}, err
+	}
+
+	return PayloadValue{raw: marshal}, nil
+}
+
+// IsEmpty 是否为空字符串或者 null
+func (v PayloadValue) IsEmpty() bool {
+	return v.text == "" && len(v.raw) == 0
+}
+
+// IsJson 是否是原生 JSON 格式
+func (v PayloadValue) IsJson() bool {
+	return len(v.raw) > 0
+}
+
+// String 字符串格式返回原始字符串，原生 JSON 格式返回 JSON 文本
+func (v PayloadValue) String() string {
+	if v.IsJson() {
+		return string(v.raw)
+	}
+
+	return v.text
+}
+
+func (v PayloadValue) MarshalJSON() ([]byte, error) {
+	if v.IsJson() {
+		return v.raw, nil
+	}
+
+	return sonic.Marshal(v.text)
+}
+
+func (v *PayloadValue) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		*v = PayloadValue{}
+		return nil
+	case data[0] == '"':
+		*v = PayloadValue{}
+		return sonic.Unmarshal(data, &v.text)
+	default:
+		buffer := &bytes.Buffer{}
+		if err := json.Compact(buffer, data); err != nil {
+			return err
+		}
+		*v = PayloadValue{raw: buffer.Bytes()}
+		return nil
+	}
+}
+
+// isObject 是否是原生 JSON 对象
+func (v PayloadValue) isObject() bool {
+	return len(v.raw) > 0 && v.raw[0] == '{'
+}
+
+// checkPayload 检查请求参数，原生 JSON 格式的 params 和 headers 只能是对象
+func checkPayload(payload *Payload) error {
+	if payload.Params.IsJson() && !payload.Params.isObject() {
+		return errors.New("params should be a string or json object: " + payload.Params.String())
+	}
+
+	if payload.Headers.IsJson() && !payload.Headers.isObject() {
+		return errors.New("headers should be a string or json object: " + payload.Headers.String())
+	}
+
+	return nil
+}
+
+// values 把 JSON 对象转换为 URL 参数，值为数组时表示同名的多个参数，值为对象时序列化为 JSON 字符串
+func (v PayloadValue) values() (url.Values, error) {
+	object := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(v.raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, errors.New("payload value is not a json object: " + err.Error())
+	}
+
+	values := make(url.Values, len(object))
+	for key, value := range object {
+		if array, ok := value.([]interface{}); ok {
+			for _, item := range array {
+				values.Add(key, formatPayloadValue(item))
+			}
+			continue
+		}
+
+		values.Add(key, formatPayloadValue(value))
+	}
+
+	return values, nil
+}
+
+// formatPayloadValue 把 JSON 中的值转换为字符串
+func formatPayloadValue(value interface{}) string {
+	switch typedValue := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typedValue
+	case json.Number:
+		return typedValue.String()
+	case bool:
+		return strconv.FormatBool(typedValue)
+	default:
+		marshal, _ := sonic.Marshal(typedValue)
+		return string(marshal)
+	}
+}
+
+// NewPayload 根据原始的请求信息创建请求参数，URL 参数、请求头、表单和 JSON 请求体使用原生 JSON 格式，便于阅读和修改
+func NewPayload(method string, requestPath string, rawQuery string, headers map[string]string, body string) (*Payload, error) {
+	payload := &Payload{
+		Method: method,
+		Path:   requestPath,
+	}
+
+	if rawQuery != "" {
+		params, err := newValuesPayloadValue(rawQuery)
+		if err != nil {
+			// 无法解析的参数保留原始格式
+			params = StringValue(url.QueryEscape(rawQuery))
+		}
+		payload.Params = params
+	}
+
+	if len(headers) > 0 {
+		value, err := JsonValue(headers)
+		if err != nil {
+			return nil, err
+		}
+		payload.Headers = value
+	}
+
+	if body == "" {
+		return payload, nil
+	}
+
+	isForm := false
+	for key, value := range headers {
+		if strings.EqualFold(key, constant.HeaderKeyContextType) && strings.HasPrefix(value, constant.ContentTypeForm) {
+			isForm = true
+		}
+	}
+
+	trimmedBody := strings.TrimSpace(body)
+	switch {
+	case isForm:
+		value, err := newValuesPayloadValue(body)
+		if err != nil {
+			value = StringValue(url.QueryEscape(body))
+		}
+		payload.Body = value
+	case json.Valid([]byte(trimmedBody)) && (strings.HasPrefix(trimmedBody, "{") || strings.HasPrefix(trimmedBody, "[")):
+		payload.Body = PayloadValue{}
+		if err := payload.Body.UnmarshalJSON([]byte(trimmedBody)); err != nil {
+			return nil, err
+		}
+	default:
+		payload.Body = StringValue(body)
+	}
+
+	return payload, nil
+}
+
+// newValuesPayloadValue 把 URL 参数格式的字符串转换为 JSON 对象，同名的多个参数转换为数组
+func newValuesPayloadValue(query string) (PayloadValue, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return PayloadValue{}, err
+	}
+
+	object := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if len(value) == 1 {
+			object[key] = value[0]
+		} else {
+			object[key] = value
+		}
+	}
+
+	return JsonValue(object)
+}
+
+// expandPayloadFiles 按英文逗号分割 payload 配置，展开其中的 glob 模式，返回相对于工作目录的文件列表
+//
+// 同一个模式匹配到的文件按文件名排序，模式没有匹配到任何文件时返回错误
+func expandPayloadFiles(workDir string, payload string) ([]string, error) {
+	files := make([]string, 0)
+	for _, pattern := range strings.Split(payload, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(workDir, pattern))
+		if err != nil {
+			return nil, errors.New("invalid payload pattern " + pattern + ": " + err.Error())
+		}
+
+		if len(matches) == 0 {
+			// 不是 glob 模式时返回文件不存在的错误
+			if _, err := os.Stat(filepath.Join(workDir, pattern)); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("no payload file matches pattern " + pattern)
+		}
+
+		for _, match := range matches {
+			file, err := filepath.Rel(workDir, match)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("payload cannot be empty")
+	}
+
+	return files, nil
 }
@@ -0,0 +1,71 @@
+package task
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPayload(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr string
+	}{
+		{name: "string", line: `{"params":"id%3D1","headers":"{\"a\":\"b\"}"}`},
+		{name: "object", line: `{"params":{"id":1},"headers":{"a":"b"}}`},
+		{name: "null", line: `{"params":null,"headers":null}`},
+		{name: "body array", line: `{"body":[1,2]}`},
+		{name: "body number", line: `{"body":5}`},
+		{name: "params number", line: `{"params":5}`, wantErr: "params should be a string or json object: 5"},
+		{name: "params bool", line: `{"params":true}`, wantErr: "params should be a string or json object: true"},
+		{name: "params array", line: `{"params":[{"id":1}]}`, wantErr: `params should be a string or json object: [{"id":1}]`},
+		{name: "headers number", line: `{"headers":1.5}`, wantErr: "headers should be a string or json object: 1.5"},
+		{name: "headers array", line: `{"headers":["a"]}`, wantErr: `headers should be a string or json object: ["a"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := &Payload{}
+			if !assert.Nil(t, sonic.UnmarshalString(tt.line, payload)) {
+				return
+			}
+
+			err := checkPayload(payload)
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+				return
+			}
+
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestReadPayloadFileRejectsNonObjectValues(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"id":"` + request.URL.Query().Get("id") + `"}`))
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	writeTestFile(t, workDir, "payload.txt", `{"params":{"id":"1"}}`, `{"params":5}`, `{"params":{"id":"3"},"headers":["a"]}`)
+
+	cfg := newTestConfig("scalar", workDir, "payload.txt")
+	cfg.UrlA = server.URL + "/a"
+	cfg.UrlB = server.URL + "/b"
+	task := runTestTask(t, cfg)
+
+	assert.Equal(t, int64(1), task.statisticsInfo.GetSameCount())
+	assert.Equal(t, int64(2), task.statisticsInfo.GetFailedCount())
+	assert.Equal(t, []string{
+		"payload.txt:2: params should be a string or json object: 5",
+		`payload.txt:3: headers should be a string or json object: ["a"]`,
+	}, task.recentErrors.list())
+}
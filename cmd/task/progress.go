@@ -0,0 +1,60 @@
+package task
+
+import (
+	"sync"
+
+	"http-diff/lib/dashboard"
+)
+
+// maxRecentErrors 最多保留的最近错误数量
+const maxRecentErrors = 3
+
+// recentErrors 保留最近的错误信息，用于在控制台展示
+type recentErrors struct {
+	lock   sync.Mutex
+	errors []string
+}
+
+func newRecentErrors() *recentErrors {
+	return &recentErrors{errors: make([]string, 0, maxRecentErrors)}
+}
+
+func (r *recentErrors) add(err string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if len(r.errors) == maxRecentErrors {
+		r.errors = r.errors[1:]
+	}
+	r.errors = append(r.errors, err)
+}
+
+func (r *recentErrors) list() []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]string(nil), r.errors...)
+}
+
+// Progress 任务当前的进度
+func (t *Task) Progress() dashboard.TaskProgress {
+	finished := false
+	select {
+	case <-t.Done():
+		finished = true
+	default:
+	}
+
+	return dashboard.TaskProgress{
+		Name:         t.Config.TaskName,
+		Total:        t.statisticsInfo.GetTotalCount(),
+		Processed:    t.statisticsInfo.GetProcessedCount(),
+		Same:         t.statisticsInfo.GetSameCount(),
+		Diff:         t.statisticsInfo.GetDiffCount(),
+		Failed:       t.statisticsInfo.GetFailedCount(),
+		Recorded:     t.statisticsInfo.GetRecordedCount(),
+		TimeLeft:     t.statisticsInfo.GetTimeLeft(),
+		RecentErrors: t.recentErrors.list(),
+		Finished:     finished,
+	}
+}
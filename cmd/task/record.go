@@ -0,0 +1,216 @@
+package task
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+
+	"http-diff/constant"
+	"http-diff/lib/logger"
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// Record 录制的请求和响应
+type Record struct {
+	Payload  *Payload  `json:"payload"`
+	Response *Response `json:"response"`
+}
+
+// recordKey 生成录制数据的 key，相同的请求参数对应同一个录制结果
+func recordKey(payload *Payload) (string, error) {
+	marshal, err := sonic.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return string(marshal), nil
+}
+
+// recordFilePath 录制文件路径
+func (t *Task) recordFilePath() string {
+	return path.Join(t.Config.WorkDir, t.Config.RecordFile)
+}
+
+// loadRecord 读取录制文件，回放模式下作为 url_a 的响应
+func (t *Task) loadRecord() error {
+	filePath := t.recordFilePath()
+
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		logger.Error(t.ctx, "Task_loadRecord Failed to open record file", zap.String("filePath", filePath), zap.Error(err))
+		return err
+	}
+
+	defer func() {
+		errInner := file.Close()
+		if errInner != nil {
+			logger.Error(t.ctx, "Task_loadRecord Failed to close record file", zap.String("filePath", filePath), zap.Error(errInner))
+		}
+	}()
+
+	// 录制文件中包含响应数据，单行最大长度和响应体最大长度保持一致
+	maxLineSize := 16 * 1024 * 1024
+	buffer := make([]byte, 0, 1024*1024)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(buffer, maxLineSize)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		lineNumber++
+
+		if len(line) == 0 {
+			continue
+		}
+
+		// 只保存原始的响应数据，每次回放时重新反序列化，避免对比时修改响应体影响其他请求
+		record := &struct {
+			Payload  *Payload        `json:"payload"`
+			Response json.RawMessage `json:"response"`
+		}{}
+		err := sonic.Unmarshal(line, record)
+		if err != nil {
+			logger.Error(t.ctx, "Task_loadRecord Failed to unmarshal record", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			return err
+		}
+
+		if record.Payload == nil || len(record.Response) == 0 || string(record.Response) == "null" {
+			logger.Error(t.ctx, "Task_loadRecord Invalid record", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber))
+			return errors.New("invalid record, payload and response cannot be empty")
+		}
+
+		if _, err := t.decodeRecordResponse(record.Response); err != nil {
+			logger.Error(t.ctx, "Task_loadRecord Failed to decode record response", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			return err
+		}
+
+		key, err := recordKey(record.Payload)
+		if err != nil {
+			return err
+		}
+
+		t.records[key] = append([]byte(nil), record.Response...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		logger.Error(t.ctx, "Task_loadRecord Error reading record file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+		return err
+	}
+
+	logger.Info(t.ctx, "Task_loadRecord Finished loading record file", zap.String("filePath", filePath), zap.Int("recordCount", len(t.records)))
+
+	return nil
+}
+
+// replayResponse 从录制数据中查找请求对应的响应
+func (t *Task) replayResponse(payload *Payload) (*Response, error) {
+	key, err := recordKey(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := t.records[key]
+	if !ok {
+		return nil, errors.New("payload not found in record file")
+	}
+
+	return t.decodeRecordResponse(data)
+}
+
+// decodeRecordResponse 反序列化录制的响应，非 JSON 格式的响应体录制时为 base64 编码的字符串
+func (t *Task) decodeRecordResponse(data []byte) (*Response, error) {
+	response := &Response{}
+	if err := sonic.Unmarshal(data, response); err != nil {
+		return nil, err
+	}
+
+	if body, ok := response.Body.(string); ok && t.Config.ResponseFormat != constant.ResponseFormatJson {
+		decoded, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+		response.Body = decoded
+	}
+
+	return response, nil
+}
+
+// record 请求 url_a 并记录响应
+func (t *Task) record(payload *Payload) {
+	response, err := t.requestUrlA(payload)
+	if err != nil {
+		logger.Error(t.ctx, "Task_record Failed to get response from UrlA", zap.Any("urlA", t.UrlAInfo), zap.Any("payload", payload), zap.Error(err))
+		t.failedCH <- NewFailedOutput(payload, errors.New("failed to get response: "+err.Error()))
+		t.statisticsInfo.AddFailed()
+		return
+	}
+
+	t.statisticsInfo.AddLatency(response.Latency, 0)
+	t.recordCh <- &Record{Payload: payload, Response: response}
+	t.statisticsInfo.AddRecorded()
+}
+
+// writeRecordToFile 用于将录制结果写入文件
+func (t *Task) writeRecordToFile() error {
+
+	recordFilePath := t.recordFilePath()
+
+	recordFile, err := t.openResultFile(recordFilePath)
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeRecordToFile Failed to create record file", zap.String("recordFilePath", recordFilePath), zap.Error(err))
+		return err
+	}
+
+	defer func() {
+		errInner := recordFile.Close()
+		if errInner != nil {
+			logger.Error(t.ctx, "Task_writeRecordToFile Failed to close record file", zap.String("recordFilePath", recordFilePath), zap.Error(errInner))
+		}
+	}()
+
+	for {
+		select {
+		case record := <-t.recordCh:
+			t.writeRecord(recordFile, record)
+		case <-t.Done():
+			// 任务结束时把通道中剩余的数据写入文件
+			for {
+				select {
+				case record := <-t.recordCh:
+					t.writeRecord(recordFile, record)
+				default:
+					logger.Debug(t.ctx, "Task_writeRecordToFile Task done, stopping writer", zap.Any("task", t))
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// writeRecord 写入一条录制结果
+func (t *Task) writeRecord(recordFile io.Writer, record *Record) {
+	t.resultLock.RLock()
+	defer t.resultLock.RUnlock()
+
+	marshal, err := sonic.Marshal(record)
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeRecordToFile Failed to marshal record", zap.Any("task", t), zap.Any("record", record), zap.Error(err))
+		t.finishPayload(record.Payload, resultRecorded)
+		return
+	}
+
+	_, err = io.WriteString(recordFile, string(marshal)+"\n")
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeRecordToFile Failed to write record to file", zap.Any("task", t), zap.Any("record", record), zap.Error(err))
+		t.finishPayload(record.Payload, resultRecorded)
+		return
+	}
+
+	t.finishPayload(record.Payload, resultRecorded)
+}
@@ -0,0 +1,89 @@
+package task
+
+import (
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"http-diff/constant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReplay(t *testing.T) {
+	tests := []struct {
+		name           string
+		responseFormat string
+		contentType    string
+	}{
+		{name: "json", responseFormat: constant.ResponseFormatJson, contentType: "application/json"},
+		{name: "text", responseFormat: constant.ResponseFormatText, contentType: "text/plain"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var requestCount atomic.Int64
+			server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+				requestCount.Add(1)
+				writer.Header().Set("Content-Type", test.contentType)
+				_, _ = writer.Write([]byte(`{"id":"` + request.URL.Query().Get("id") + `","items":[1,2]}`))
+			}))
+			defer server.Close()
+
+			workDir := t.TempDir()
+			writeTestFile(t, workDir, "record.txt", `{"params":{"id":"1"}}`, `{"params":{"id":"2"}}`, `{"params":{"id":"3"}}`)
+			writeTestFile(t, workDir, "replay.txt", `{"params":{"id":"1"}}`, `{"params":{"id":"2"}}`, `{"params":{"id":"3"}}`, `{"params":{"id":"4"}}`)
+
+			recordConfig := newTestConfig("record_"+test.name, workDir, "record.txt")
+			recordConfig.Mode = constant.ModeRecord
+			recordConfig.ResponseFormat = test.responseFormat
+			recordConfig.UrlA = server.URL + "/user"
+			recordTask := runTestTask(t, recordConfig)
+
+			assert.Equal(t, int64(3), recordTask.statisticsInfo.GetRecordedCount())
+			assert.Equal(t, int64(0), recordTask.statisticsInfo.GetFailedCount())
+			assert.Equal(t, int64(3), requestCount.Load())
+			assert.Len(t, readTestFile(t, workDir, recordConfig.RecordFile), 3)
+
+			// 回放时不请求 url_a，录制文件中找不到的请求记录为失败
+			replayConfig := newTestConfig("replay_"+test.name, workDir, "replay.txt")
+			replayConfig.Mode = constant.ModeReplay
+			replayConfig.ResponseFormat = test.responseFormat
+			replayConfig.RecordFile = recordConfig.RecordFile
+			replayConfig.UrlA = "http://127.0.0.1:1"
+			replayConfig.UrlB = server.URL + "/user"
+			replayTask := runTestTask(t, replayConfig)
+
+			assert.Equal(t, int64(3), replayTask.statisticsInfo.GetSameCount())
+			assert.Equal(t, int64(0), replayTask.statisticsInfo.GetDiffCount())
+			assert.Equal(t, int64(1), replayTask.statisticsInfo.GetFailedCount())
+			assert.Equal(t, int64(7), requestCount.Load())
+
+			failed := readTestFile(t, workDir, replayConfig.TaskName+"_failed_payload.txt")
+			if assert.Len(t, failed, 1) {
+				assert.Contains(t, failed[0], `"params":{"id":"4"}`)
+				assert.Contains(t, failed[0], "payload not found in record file")
+			}
+		})
+	}
+}
+
+func TestReplayResponseIsNotShared(t *testing.T) {
+	task := &Task{Config: Config{ResponseFormat: constant.ResponseFormatJson}, records: make(map[string][]byte)}
+	payload := &Payload{Path: "/user"}
+	key, err := recordKey(payload)
+	assert.Nil(t, err)
+	task.records[key] = []byte(`{"statusCode":200,"body":{"id":1}}`)
+
+	first, err := task.replayResponse(payload)
+	assert.Nil(t, err)
+	first.Body.(map[string]interface{})["id"] = 2
+
+	second, err := task.replayResponse(payload)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, second.Body)
+
+	_, err = task.replayResponse(&Payload{Path: "/other"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,200 @@
+package task
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+
+	"http-diff/lib/logger"
+	"http-diff/util"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxReportSamples 每个路径最多记录的请求参数数量
+	maxReportSamples = 3
+
+	// reportPathStatusCode 状态码差异在报告中的路径
+	reportPathStatusCode = "#statusCode"
+	// reportPathHeaders 响应头差异在报告中的路径
+	reportPathHeaders = "#headers"
+	// reportPathBody 非 JSON 格式的响应体差异在报告中的路径
+	reportPathBody = "$"
+	// reportPathLatency 性能差异在报告中的路径
+	reportPathLatency = "#latency"
+)
+
+// DiffReport 任务结束时生成的差异汇总报告
+type DiffReport struct {
+	TaskName    string `json:"taskName"`
+	TotalCount  int64  `json:"totalCount"`
+	SameCount   int64  `json:"sameCount"`
+	DiffCount   int64  `json:"diffCount"`
+	FailedCount int64  `json:"failedCount"`
+	TimeCost    string `json:"timeCost"`
+
+	// PerformanceDiffCount 接口B明显比接口A慢的数量
+	PerformanceDiffCount int64          `json:"performanceDiffCount"`
+	UrlALatency          LatencySummary `json:"urlALatency"`
+	UrlBLatency          LatencySummary `json:"urlBLatency"`
+
+	// Paths 按有差异的请求数量从多到少排序
+	Paths []*PathReport `json:"paths"`
+}
+
+// PathReport 单个路径的差异汇总
+type PathReport struct {
+	// Path 规范化之后的路径，数组下标被替换为 [*]
+	Path string `json:"path"`
+	// Count 该路径有差异的请求数量
+	Count int64 `json:"count"`
+	// Percentage 该路径有差异的请求占所有有差异的请求的比例，#latency 为性能差异占所有对比的请求的比例
+	Percentage string `json:"percentage"`
+	// Kinds 各个差异类型出现的次数
+	Kinds map[string]int64 `json:"kinds,omitempty"`
+	// Samples 有差异的请求参数示例
+	Samples []*Payload `json:"samples"`
+}
+
+// reportCollector 汇总对比结果中的差异
+type reportCollector struct {
+	lock  sync.Mutex
+	paths map[string]*PathReport
+	// diffCount 汇总的响应有差异的请求数量，不包括只有性能差异的请求
+	diffCount int64
+}
+
+func newReportCollector() *reportCollector {
+	return &reportCollector{
+		paths: make(map[string]*PathReport),
+	}
+}
+
+// Add 汇总一条对比结果，同一个请求中相同路径的差异只计数一次
+func (c *reportCollector) Add(output *OutPut) {
+	if output == nil || (!output.HasDiff() && !output.PerformanceDiff) {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if output.HasDiff() {
+		c.diffCount++
+	}
+
+	kinds := make(map[string]map[string]int64)
+	addPath := func(path, kind string) {
+		if kinds[path] == nil {
+			kinds[path] = make(map[string]int64)
+		}
+		if kind != "" {
+			kinds[path][kind]++
+		}
+	}
+
+	for _, difference := range output.Differences {
+		addPath(util.NormalizeJsonPath(difference.Path), difference.Kind)
+	}
+
+	// 没有结构化差异时（非 JSON 格式）按整个响应体汇总
+	if output.Diff != "" && len(output.Differences) == 0 {
+		addPath(reportPathBody, "")
+	}
+
+	if output.StatusDiff != "" {
+		addPath(reportPathStatusCode, "")
+	}
+
+	if output.HeaderDiff != "" {
+		addPath(reportPathHeaders, "")
+	}
+
+	if output.PerformanceDiff {
+		addPath(reportPathLatency, "")
+	}
+
+	for path, kindCount := range kinds {
+		report, ok := c.paths[path]
+		if !ok {
+			report = &PathReport{Path: path, Kinds: make(map[string]int64)}
+			c.paths[path] = report
+		}
+
+		report.Count++
+		for kind, count := range kindCount {
+			report.Kinds[kind] += count
+		}
+
+		if len(report.Samples) < maxReportSamples {
+			report.Samples = append(report.Samples, output.Payload)
+		}
+	}
+}
+
+// Report 生成差异汇总报告
+func (c *reportCollector) Report(taskName string, statisticsInfo *StatisticsInfo) *DiffReport {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	report := &DiffReport{
+		TaskName:    taskName,
+		TotalCount:  statisticsInfo.GetTotalCount(),
+		SameCount:   statisticsInfo.GetSameCount(),
+		DiffCount:   statisticsInfo.GetDiffCount(),
+		FailedCount: statisticsInfo.GetFailedCount(),
+		TimeCost:    statisticsInfo.GetTimeCost(),
+
+		PerformanceDiffCount: statisticsInfo.GetPerformanceDiffCount(),
+		UrlALatency:          statisticsInfo.GetUrlALatency(),
+		UrlBLatency:          statisticsInfo.GetUrlBLatency(),
+		Paths:                make([]*PathReport, 0, len(c.paths)),
+	}
+
+	for _, pathReport := range c.paths {
+		total := c.diffCount
+		if pathReport.Path == reportPathLatency {
+			total = statisticsInfo.GetSameCount() + statisticsInfo.GetDiffCount()
+		}
+
+		percentage := float64(0)
+		if total > 0 {
+			percentage = float64(pathReport.Count) / float64(total)
+		}
+		pathReport.Percentage = strconv.FormatFloat(percentage*100, 'f', 2, 64) + "%"
+		report.Paths = append(report.Paths, pathReport)
+	}
+
+	sort.Slice(report.Paths, func(i, j int) bool {
+		if report.Paths[i].Count != report.Paths[j].Count {
+			return report.Paths[i].Count > report.Paths[j].Count
+		}
+		return report.Paths[i].Path < report.Paths[j].Path
+	})
+
+	return report
+}
+
+// writeReportToFile 把差异汇总报告写入工作目录
+func (t *Task) writeReportToFile() {
+	reportFilePath := path.Join(t.Config.WorkDir, t.Config.TaskName+"_report.json")
+
+	report := t.reportCollector.Report(t.Config.TaskName, t.statisticsInfo)
+	marshal, err := sonic.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeReportToFile Failed to marshal report", zap.String("reportFilePath", reportFilePath), zap.Error(err))
+		return
+	}
+
+	err = os.WriteFile(reportFilePath, marshal, 0644)
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeReportToFile Failed to write report file", zap.String("reportFilePath", reportFilePath), zap.Error(err))
+		return
+	}
+
+	logger.Info(t.ctx, "Task_writeReportToFile Report written", zap.String("reportFilePath", reportFilePath), zap.Int("pathCount", len(report.Paths)))
+}
@@ -0,0 +1,41 @@
+package task
+
+import (
+	"testing"
+
+	"http-diff/util"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportCollectorPercentage(t *testing.T) {
+	collector := newReportCollector()
+	collector.Add(&OutPut{Diff: "-", Differences: []util.Difference{{Path: "$.items[0].id", Kind: "changed"}, {Path: "$.items[1].id", Kind: "changed"}}})
+	collector.Add(&OutPut{Diff: "-", Differences: []util.Difference{{Path: "$.items[2].id", Kind: "removed"}}})
+	collector.Add(&OutPut{StatusDiff: "- 200\n+ 500\n", PerformanceDiff: true})
+	// 只有性能差异的请求不计入响应差异的比例
+	collector.Add(&OutPut{PerformanceDiff: true})
+	collector.Add(&OutPut{})
+
+	statisticsInfo := NewStatisticsInfo()
+	for i := 0; i < 5; i++ {
+		statisticsInfo.AddSame()
+	}
+	for i := 0; i < 3; i++ {
+		statisticsInfo.AddDiff()
+	}
+
+	report := collector.Report("test", statisticsInfo)
+	percentages := make(map[string]string)
+	counts := make(map[string]int64)
+	kinds := make(map[string]map[string]int64)
+	for _, pathReport := range report.Paths {
+		percentages[pathReport.Path] = pathReport.Percentage
+		counts[pathReport.Path] = pathReport.Count
+		kinds[pathReport.Path] = pathReport.Kinds
+	}
+
+	assert.Equal(t, map[string]int64{"$.items[*].id": 2, reportPathStatusCode: 1, reportPathLatency: 2}, counts)
+	assert.Equal(t, map[string]string{"$.items[*].id": "66.67%", reportPathStatusCode: "33.33%", reportPathLatency: "25.00%"}, percentages)
+	assert.Equal(t, map[string]int64{"changed": 2, "removed": 1}, kinds["$.items[*].id"])
+}
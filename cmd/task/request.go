@@ -2,8 +2,13 @@ package task
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	nethttp "net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"http-diff/constant"
 	"http-diff/lib/http"
@@ -14,7 +19,7 @@ import (
 )
 
 // DoRequest 发送请求
-func DoRequest(ctx context.Context, taskInfo *Info, payload *Payload) (interface{}, error) {
+func DoRequest(ctx context.Context, taskInfo *Info, payload *Payload) (*Response, error) {
 	logger.Debug(ctx, "DoRequest start, request info", zap.Any("taskInfo", taskInfo), zap.Any("payload", payload))
 
 	parseUrl, err := url.Parse(taskInfo.Url)
@@ -23,26 +28,42 @@ func DoRequest(ctx context.Context, taskInfo *Info, payload *Payload) (interface
 		return nil, err
 	}
 
-	if payload.Params != "" {
-		queryUnescape, err := url.QueryUnescape(payload.Params)
+	if payload.Path != "" {
+		err = joinPayloadPath(parseUrl, payload.Path)
 		if err != nil {
-			logger.Error(ctx, "DoRequest url.QueryUnescape error", zap.Any("payload.Params", payload.Params), zap.Error(err))
+			logger.Error(ctx, "DoRequest joinPayloadPath error", zap.Any("taskInfo", taskInfo), zap.String("path", payload.Path), zap.Error(err))
 			return nil, err
 		}
+	}
 
-		parseQuery, err := url.ParseQuery(queryUnescape)
-		if err != nil {
-			logger.Error(ctx, "DoRequest url.ParseQuery error", zap.Any("payload.Params", payload.Params), zap.Error(err))
-			return nil, err
-		}
+	if rewritePath := taskInfo.Rewrite.RewritePath(parseUrl.Path); rewritePath != parseUrl.Path {
+		parseUrl.Path = rewritePath
+		parseUrl.RawPath = ""
+	}
+
+	method := taskInfo.Method
+	if payload.Method != "" {
+		method = strings.ToUpper(payload.Method)
+	}
 
+	if !payload.Params.IsEmpty() || taskInfo.Rewrite.HasParamRules() {
 		query := parseUrl.Query()
-		for key, value := range parseQuery {
-			for _, subValue := range value {
-				query.Add(key, subValue)
+
+		if !payload.Params.IsEmpty() {
+			parseQuery, err := parseValues(payload.Params)
+			if err != nil {
+				logger.Error(ctx, "DoRequest parse params error", zap.Any("payload.Params", payload.Params), zap.Error(err))
+				return nil, err
+			}
+
+			for key, value := range parseQuery {
+				for _, subValue := range value {
+					query.Add(key, subValue)
+				}
 			}
 		}
 
+		taskInfo.Rewrite.RewriteParams(query)
 		parseUrl.RawQuery = query.Encode()
 	}
 
@@ -57,75 +78,181 @@ func DoRequest(ctx context.Context, taskInfo *Info, payload *Payload) (interface
 	}
 	logger.Debug(ctx, "DoRequest header", zap.Any("header", header))
 
-	// 处理 GET 请求
-	var result interface{}
-	if taskInfo.Method == constant.GET {
-		err := http.Get(ctx, requestUrl, nil, header, &result)
+	// GET、HEAD 请求没有请求体，POST、PUT、PATCH、DELETE 请求体为空时不发送请求体
+	var params interface{}
+	switch method {
+	case constant.GET, constant.HEAD:
+	case constant.POST, constant.PUT, constant.PATCH, constant.DELETE:
+		params, err = initPostParams(taskInfo, payload, http.ContentType(header))
 		if err != nil {
-			logger.Error(ctx, "DoRequest http.Get error", zap.String("url", requestUrl), zap.Any("header", header), zap.Error(err))
+			logger.Error(ctx, "DoRequest initPostParams error", zap.Any("taskInfo", taskInfo), zap.Any("payload", payload), zap.Error(err))
 			return nil, err
 		}
-		return result, nil
+	default:
+		// 未知类型请求
+		return nil, errors.New("unsupported method: " + method)
 	}
 
-	// 处理 POST 请求
-	if taskInfo.Method == constant.POST {
-		params, err := initPostParams(taskInfo, payload)
-		if err != nil {
-			logger.Error(ctx, "DoRequest initPostParams error", zap.Any("taskInfo", taskInfo), zap.Any("payload", payload), zap.Error(err))
-			return nil, err
+	start := time.Now()
+	httpResponse, err := http.Send(ctx, method, requestUrl, params, header)
+	latency := time.Since(start)
+	if err != nil {
+		logger.Error(ctx, "DoRequest http.Send error", zap.String("method", method), zap.String("url", requestUrl), zap.Any("params", params), zap.Any("header", header), zap.Error(err))
+		return nil, err
+	}
+
+	response, err := newResponse(taskInfo, method, httpResponse)
+	if err != nil {
+		logger.Error(ctx, "DoRequest newResponse error", zap.String("method", method), zap.String("url", requestUrl), zap.Int("statusCode", httpResponse.StatusCode), zap.String("body", string(httpResponse.Body)), zap.Error(err))
+		return nil, err
+	}
+
+	response.Latency = latency
+	return response, nil
+}
+
+// joinPayloadPath 把请求参数中的路径拼接在 URL 的路径后面，路径中的 URL 参数合并到 URL 的参数中
+//
+// 不使用 url.JoinPath，保留路径中的 .. 和转义字符，原样发送
+func joinPayloadPath(parseUrl *url.URL, payloadPath string) error {
+	pathUrl, err := url.Parse(payloadPath)
+	if err != nil {
+		return err
+	}
+
+	if pathUrl.Scheme != "" || pathUrl.Host != "" {
+		return errors.New("payload path should not contain scheme or host: " + payloadPath)
+	}
+
+	parseUrl.RawPath = joinUrlPath(parseUrl.EscapedPath(), pathUrl.EscapedPath())
+	parseUrl.Path = joinUrlPath(parseUrl.Path, pathUrl.Path)
+
+	if pathUrl.RawQuery != "" {
+		if parseUrl.RawQuery != "" {
+			parseUrl.RawQuery += "&"
 		}
+		parseUrl.RawQuery += pathUrl.RawQuery
+	}
 
-		err = http.Post(ctx, requestUrl, params, header, &result)
-		if err != nil {
-			logger.Error(ctx, "DoRequest http.Post error", zap.String("url", requestUrl), zap.Any("params", params), zap.Any("header", header), zap.Error(err))
+	return nil
+}
+
+// joinUrlPath 用一个 / 连接两段路径
+func joinUrlPath(base, elem string) string {
+	if elem == "" {
+		return base
+	}
+
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(elem, "/")
+}
+
+// newResponse 根据任务配置把原始响应转换为用于对比的响应信息
+//
+// 不对比状态码时，状态码不是 200 的请求会被当成错误。JSON 格式的响应体会被反序列化，状态码不是 200 且响应体不是 JSON 时保留原始文本
+func newResponse(taskInfo *Info, method string, httpResponse *http.Response) (*Response, error) {
+	if !taskInfo.CompareStatusCode && httpResponse.StatusCode != nethttp.StatusOK {
+		return nil, errors.New("data request failed , code:" + strconv.Itoa(httpResponse.StatusCode))
+	}
+
+	response := &Response{
+		StatusCode: httpResponse.StatusCode,
+	}
+
+	if len(taskInfo.CompareHeaders) > 0 {
+		response.Headers = make(map[string][]string, len(taskInfo.CompareHeaders))
+		for _, key := range taskInfo.CompareHeaders {
+			if values := httpResponse.Header.Values(key); len(values) > 0 {
+				response.Headers[nethttp.CanonicalHeaderKey(key)] = values
+			}
+		}
+	}
+
+	// HEAD 请求没有响应体
+	if method == constant.HEAD {
+		return response, nil
+	}
+
+	if taskInfo.ResponseFormat != constant.ResponseFormatJson {
+		response.Body = httpResponse.Body
+		return response, nil
+	}
+
+	err := sonic.Unmarshal(httpResponse.Body, &response.Body)
+	if err != nil {
+		if httpResponse.StatusCode == nethttp.StatusOK {
 			return nil, err
 		}
 
-		return result, nil
+		response.Body = string(httpResponse.Body)
 	}
 
-	// 位置类型请求
-	return nil, errors.New("unsupported method: " + taskInfo.Method)
+	return response, nil
 }
 
+// initHeader 初始化请求头，请求参数中的 Content-Type 优先于任务配置的内容类型，请求头名称不区分大小写
 func initHeader(taskInfo *Info, payload *Payload) (map[string]string, error) {
 	result := make(map[string]string)
 
-	if taskInfo.ContentType != "" {
-		result[constant.HeaderKeyContextType] = taskInfo.ContentType
-	}
+	if payload.Headers.IsJson() {
+		values, err := payload.Headers.values()
+		if err != nil {
+			return nil, err
+		}
 
-	if payload.Headers != "" {
-		err := sonic.Unmarshal([]byte(payload.Headers), &result)
+		// 同名的多个请求头用逗号合并
+		for key, value := range values {
+			result[key] = strings.Join(value, ", ")
+		}
+	} else if !payload.Headers.IsEmpty() {
+		err := sonic.Unmarshal([]byte(payload.Headers.String()), &result)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if taskInfo.ContentType != "" && !hasHeader(result, constant.HeaderKeyContextType) {
+		result[constant.HeaderKeyContextType] = taskInfo.ContentType
+	}
+
+	taskInfo.Rewrite.RewriteHeaders(result)
+
 	return result, nil
 }
 
-func initPostParams(taskInfo *Info, payload *Payload) (interface{}, error) {
+// hasHeader 是否包含请求头，请求头名称不区分大小写
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// initPostParams 初始化请求体，contentType 为实际发送的 Content-Type
+func initPostParams(taskInfo *Info, payload *Payload, contentType string) (interface{}, error) {
 	var params interface{}
-	if payload.Body == "" {
+	if payload.Body.IsEmpty() {
 		return params, nil
 	}
 
-	if taskInfo.ContentType == constant.ContentTypeForm {
-		formUnescape, err := url.QueryUnescape(payload.Body)
-		if err != nil {
-			return nil, err
-		}
+	if taskInfo.Rewrite.HasBodyRules() {
+		return rewriteBody(taskInfo, payload, contentType)
+	}
 
-		formParams, err := url.ParseQuery(formUnescape)
+	if contentType == constant.ContentTypeForm {
+		formParams, err := parseValues(payload.Body)
 		if err != nil {
 			return nil, err
 		}
 
 		params = formParams.Encode()
+	} else if payload.Body.IsJson() {
+		// 原生 JSON 在读取时已经校验过，直接作为请求体
+		params = json.RawMessage(payload.Body.String())
 	} else {
-		err := sonic.Unmarshal([]byte(payload.Body), &params)
+		err := sonic.Unmarshal([]byte(payload.Body.String()), &params)
 		if err != nil {
 			return nil, err
 		}
@@ -133,3 +260,78 @@ func initPostParams(taskInfo *Info, payload *Payload) (interface{}, error) {
 
 	return params, nil
 }
+
+// rewriteBody 按照改写规则修改请求体，表单请求的参数按照 JSON 对象改写，值为数组时表示同名的多个参数
+func rewriteBody(taskInfo *Info, payload *Payload, contentType string) (interface{}, error) {
+	if contentType == constant.ContentTypeForm {
+		formParams, err := parseValues(payload.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		body := make(map[string]interface{}, len(formParams))
+		for key, value := range formParams {
+			if len(value) == 1 {
+				body[key] = value[0]
+				continue
+			}
+
+			items := make([]interface{}, 0, len(value))
+			for _, item := range value {
+				items = append(items, item)
+			}
+			body[key] = items
+		}
+
+		if err := taskInfo.Rewrite.RewriteBody(body); err != nil {
+			return nil, err
+		}
+
+		rewriteParams := make(url.Values, len(body))
+		for key, value := range body {
+			if items, ok := value.([]interface{}); ok {
+				for _, item := range items {
+					rewriteParams.Add(key, formatPayloadValue(item))
+				}
+				continue
+			}
+
+			rewriteParams.Add(key, formatPayloadValue(value))
+		}
+
+		return rewriteParams.Encode(), nil
+	}
+
+	// 使用 json.Number 保留数字的精度
+	var body interface{}
+	decoder := json.NewDecoder(strings.NewReader(payload.Body.String()))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+
+	if err := taskInfo.Rewrite.RewriteBody(body); err != nil {
+		return nil, err
+	}
+
+	marshal, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(marshal), nil
+}
+
+// parseValues 解析 URL 参数格式的值，字符串格式需要先进行 URL 解码，JSON 格式为对象
+func parseValues(value PayloadValue) (url.Values, error) {
+	if value.IsJson() {
+		return value.values()
+	}
+
+	unescape, err := url.QueryUnescape(value.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return url.ParseQuery(unescape)
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"http-diff/constant"
+	"http-diff/lib/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoRequestPayloadOverride(t *testing.T) {
+	var method, requestUri string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		method = request.Method
+		requestUri = request.RequestURI
+		_, _ = writer.Write([]byte(`{"code":0}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		payload    string
+		method     string
+		requestUri string
+	}{
+		{name: "task method", url: server.URL + "/api", payload: `{}`, method: constant.GET, requestUri: "/api"},
+		{name: "method override", url: server.URL + "/api", payload: `{"method":"put","body":{"id":1}}`, method: constant.PUT, requestUri: "/api"},
+		{name: "path", url: server.URL + "/api", payload: `{"path":"/user/1"}`, method: constant.GET, requestUri: "/api/user/1"},
+		{name: "path without slash", url: server.URL + "/api/", payload: `{"path":"user/1"}`, method: constant.GET, requestUri: "/api/user/1"},
+		{name: "path with query", url: server.URL + "/api", payload: `{"path":"/user?id=1"}`, method: constant.GET, requestUri: "/api/user?id=1"},
+		{name: "path query and params", url: server.URL + "/api?v=2", payload: `{"path":"/user?id=1","params":{"name":"a"}}`, method: constant.GET, requestUri: "/api/user?id=1&name=a&v=2"},
+		{name: "path keeps dot segments", url: server.URL + "/api", payload: `{"path":"/a/../b"}`, method: constant.GET, requestUri: "/api/a/../b"},
+		{name: "path keeps escaped chars", url: server.URL + "/api", payload: `{"path":"/a%2Fb"}`, method: constant.GET, requestUri: "/api/a%2Fb"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload := &Payload{}
+			assert.Nil(t, sonic.Unmarshal([]byte(test.payload), payload))
+
+			taskInfo := &Info{Method: constant.GET, Url: test.url, ResponseFormat: constant.ResponseFormatJson}
+			_, err := DoRequest(context.Background(), taskInfo, payload)
+			assert.Nil(t, err)
+			assert.Equal(t, test.method, method)
+			assert.Equal(t, test.requestUri, requestUri)
+		})
+	}
+}
+
+func TestDoRequestPayloadPathWithHost(t *testing.T) {
+	payload := &Payload{Path: "http://127.0.0.1/user"}
+	taskInfo := &Info{Method: constant.GET, Url: "http://127.0.0.1:1/api", ResponseFormat: constant.ResponseFormatJson}
+
+	_, err := DoRequest(context.Background(), taskInfo, payload)
+	assert.NotNil(t, err)
+}
+
+func TestNewResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *Info
+		method   string
+		response *http.Response
+		body     interface{}
+		hasErr   bool
+	}{
+		{name: "json", info: &Info{ResponseFormat: constant.ResponseFormatJson}, method: constant.GET, response: &http.Response{StatusCode: 200, Body: []byte(`{"id":1}`)}, body: map[string]interface{}{"id": float64(1)}},
+		{name: "invalid json", info: &Info{ResponseFormat: constant.ResponseFormatJson}, method: constant.GET, response: &http.Response{StatusCode: 200, Body: []byte(`<html>`)}, hasErr: true},
+		{name: "text", info: &Info{ResponseFormat: constant.ResponseFormatText}, method: constant.GET, response: &http.Response{StatusCode: 200, Body: []byte(`{"id":1}`)}, body: []byte(`{"id":1}`)},
+		{name: "head", info: &Info{ResponseFormat: constant.ResponseFormatJson}, method: constant.HEAD, response: &http.Response{StatusCode: 200}},
+		{name: "status not compared", info: &Info{ResponseFormat: constant.ResponseFormatJson}, method: constant.GET, response: &http.Response{StatusCode: 500, Body: []byte(`error`)}, hasErr: true},
+		{name: "error page", info: &Info{ResponseFormat: constant.ResponseFormatJson, CompareStatusCode: true}, method: constant.GET, response: &http.Response{StatusCode: 500, Body: []byte(`error`)}, body: "error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response, err := newResponse(test.info, test.method, test.response)
+			if test.hasErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.response.StatusCode, response.StatusCode)
+			assert.Equal(t, test.body, response.Body)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package task
+
+import (
+	"time"
+)
+
+// Response 响应信息
+type Response struct {
+	// StatusCode 状态码
+	StatusCode int `json:"statusCode"`
+	// Headers 需要对比的响应头
+	Headers map[string][]string `json:"headers,omitempty"`
+	// Body 响应体，JSON 格式为反序列化后的数据，其余格式为原始响应体
+	Body interface{} `json:"body"`
+	// Latency 请求耗时，录制模式下会记录到录制文件中，回放时作为接口A的耗时
+	Latency time.Duration `json:"latency,omitempty"`
+}
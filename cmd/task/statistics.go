@@ -4,14 +4,21 @@ import (
 	"strconv"
 	"sync/atomic"
 	"time"
+
+	"http-diff/util"
 )
 
+// unknownTotalCount 还没有统计出请求参数总行数
+const unknownTotalCount = -1
+
 type StatisticsInfo struct {
-	// totalCount 总请求数量
-	totalCount int64
+	// totalCount 总请求数量，还没有统计出来时为 unknownTotalCount
+	totalCount *atomic.Int64
 
 	// startTime 任务开始时间
 	startTime time.Time
+	// restoredCount 断点续跑时恢复的已处理数量，估算剩余时间时只按本次运行处理的数量计算速度
+	restoredCount int64
 
 	//failedCount 失败的数量
 	failedCount *atomic.Int64
@@ -22,22 +29,61 @@ type StatisticsInfo struct {
 	//sameCount 没有diff的数量
 	sameCount *atomic.Int64
 
+	// recordedCount 录制模式下录制成功的数量
+	recordedCount *atomic.Int64
+
 	// lastStatisticsTime 上次统计时间
 	lastStatisticsTime time.Time
 	// lastStatisticsCount 上次统计的数量
 	lastStatisticsCount int64
+
+	// urlARequestCount 请求接口A的次数
+	urlARequestCount *atomic.Int64
+	// urlBRequestCount 请求接口B的次数
+	urlBRequestCount *atomic.Int64
+	// lastUrlARequestCount 上次统计时请求接口A的次数
+	lastUrlARequestCount int64
+	// lastUrlBRequestCount 上次统计时请求接口B的次数
+	lastUrlBRequestCount int64
+
+	// performanceDiffCount 接口B明显比接口A慢的数量
+	performanceDiffCount *atomic.Int64
+	// urlALatency 接口A的耗时分布
+	urlALatency *util.Histogram
+	// urlBLatency 接口B的耗时分布
+	urlBLatency *util.Histogram
 }
 
-func NewStatisticsInfo(totalCount int) *StatisticsInfo {
+// LatencySummary 耗时分布
+type LatencySummary struct {
+	Count int64  `json:"count"`
+	P50   string `json:"p50"`
+	P90   string `json:"p90"`
+	P99   string `json:"p99"`
+	Max   string `json:"max"`
+}
+
+func NewStatisticsInfo() *StatisticsInfo {
 
 	s := &StatisticsInfo{
-		totalCount:  int64(totalCount),
+		totalCount:  &atomic.Int64{},
 		startTime:   time.Now(),
 		failedCount: &atomic.Int64{},
 		diffCount:   &atomic.Int64{},
 		sameCount:   &atomic.Int64{},
+
+		recordedCount: &atomic.Int64{},
+
+		lastStatisticsTime: time.Now(),
+		urlARequestCount:   &atomic.Int64{},
+		urlBRequestCount:   &atomic.Int64{},
+
+		performanceDiffCount: &atomic.Int64{},
+		urlALatency:          util.NewHistogram(),
+		urlBLatency:          util.NewHistogram(),
 	}
 
+	s.totalCount.Store(unknownTotalCount)
 	s.failedCount.Store(0)
 	s.diffCount.Store(0)
 	s.sameCount.Store(0)
@@ -45,6 +91,16 @@ func NewStatisticsInfo(totalCount int) *StatisticsInfo {
 	return s
 }
 
+// Restore 断点续跑时恢复上次运行的统计信息
+func (s *StatisticsInfo) Restore(sameCount, diffCount, failedCount, recordedCount int64) {
+	s.sameCount.Store(sameCount)
+	s.diffCount.Store(diffCount)
+	s.failedCount.Store(failedCount)
+	s.recordedCount.Store(recordedCount)
+	s.lastStatisticsCount = s.GetProcessedCount()
+	s.restoredCount = s.GetProcessedCount()
+}
+
 func (s *StatisticsInfo) AddFailed() {
 	s.failedCount.Add(1)
 }
@@ -57,8 +113,74 @@ func (s *StatisticsInfo) AddSame() {
 	s.sameCount.Add(1)
 }
 
+// AddRecorded 记录一次录制成功
+func (s *StatisticsInfo) AddRecorded() {
+	s.recordedCount.Add(1)
+}
+
+// AddUrlARequest 记录一次接口A的请求
+func (s *StatisticsInfo) AddUrlARequest() {
+	s.urlARequestCount.Add(1)
+}
+
+// AddUrlBRequest 记录一次接口B的请求
+func (s *StatisticsInfo) AddUrlBRequest() {
+	s.urlBRequestCount.Add(1)
+}
+
+// AddLatency 记录两个接口的耗时，耗时为 0 表示没有请求对应的接口
+func (s *StatisticsInfo) AddLatency(urlALatency, urlBLatency time.Duration) {
+	if urlALatency > 0 {
+		s.urlALatency.Record(urlALatency)
+	}
+
+	if urlBLatency > 0 {
+		s.urlBLatency.Record(urlBLatency)
+	}
+}
+
+// AddPerformanceDiff 记录一次性能差异
+func (s *StatisticsInfo) AddPerformanceDiff() {
+	s.performanceDiffCount.Add(1)
+}
+
+func (s *StatisticsInfo) GetPerformanceDiffCount() int64 {
+	return s.performanceDiffCount.Load()
+}
+
+// GetUrlALatency 接口A的耗时分布
+func (s *StatisticsInfo) GetUrlALatency() LatencySummary {
+	return newLatencySummary(s.urlALatency)
+}
+
+// GetUrlBLatency 接口B的耗时分布
+func (s *StatisticsInfo) GetUrlBLatency() LatencySummary {
+	return newLatencySummary(s.urlBLatency)
+}
+
+func newLatencySummary(histogram *util.Histogram) LatencySummary {
+	return LatencySummary{
+		Count: histogram.Count(),
+		P50:   histogram.Quantile(0.5).Round(time.Microsecond).String(),
+		P90:   histogram.Quantile(0.9).Round(time.Microsecond).String(),
+		P99:   histogram.Quantile(0.99).Round(time.Microsecond).String(),
+		Max:   histogram.Max().Round(time.Microsecond).String(),
+	}
+}
+
+// SetTotalCount 设置请求参数总行数
+func (s *StatisticsInfo) SetTotalCount(totalCount int64) {
+	s.totalCount.Store(totalCount)
+}
+
+// SetTotalCountIfUnknown 总行数还没有设置时设置总行数，返回是否设置成功
+func (s *StatisticsInfo) SetTotalCountIfUnknown(totalCount int64) bool {
+	return s.totalCount.CompareAndSwap(unknownTotalCount, totalCount)
+}
+
+// GetTotalCount 请求参数总行数，还没有统计出来时返回 -1
 func (s *StatisticsInfo) GetTotalCount() int64 {
-	return s.totalCount
+	return s.totalCount.Load()
 }
 
 func (s *StatisticsInfo) GetTimeCost() string {
@@ -66,8 +188,14 @@ func (s *StatisticsInfo) GetTimeCost() string {
 }
 
 func (s *StatisticsInfo) GetTimeLeft() string {
+	// 本次运行还没有处理完成的请求或者不知道总行数时无法估算
+	processedCount := s.GetProcessedCount() - s.restoredCount
+	if processedCount <= 0 || s.GetTotalCount() == unknownTotalCount {
+		return "-"
+	}
+
 	runTime := time.Since(s.startTime).Seconds()
-	timeLeft := float64(s.GetTotalCount()-s.GetProcessedCount()) * runTime / float64(s.GetProcessedCount())
+	timeLeft := float64(s.GetTotalCount()-s.GetProcessedCount()) * runTime / float64(processedCount)
 
 	return strconv.FormatFloat(timeLeft, 'f', 0, 64) + "s"
 }
@@ -84,11 +212,20 @@ func (s *StatisticsInfo) GetSameCount() int64 {
 	return s.sameCount.Load()
 }
 
+// GetRecordedCount 录制成功的数量
+func (s *StatisticsInfo) GetRecordedCount() int64 {
+	return s.recordedCount.Load()
+}
+
 func (s *StatisticsInfo) GetProcessedCount() int64 {
-	return s.GetSameCount() + s.GetFailedCount() + s.GetDiffCount()
+	return s.GetSameCount() + s.GetFailedCount() + s.GetDiffCount() + s.GetRecordedCount()
 }
 
 func (s *StatisticsInfo) GetProgress() string {
+	if s.GetTotalCount() == unknownTotalCount {
+		return "unknown total"
+	}
+
 	progressFloat := float64(s.GetProcessedCount()) / float64(s.GetTotalCount())
 	return strconv.FormatFloat(progressFloat*100, 'f', 2, 64) + "%"
 }
@@ -98,7 +235,21 @@ func (s *StatisticsInfo) GetRate() string {
 	return strconv.FormatFloat(rateFloat, 'f', 0, 64) + " req/s"
 }
 
+// GetUrlARate 上次统计之后实际请求接口A的速率，受限流配置影响
+func (s *StatisticsInfo) GetUrlARate() string {
+	rateFloat := float64(s.urlARequestCount.Load()-s.lastUrlARequestCount) / time.Since(s.lastStatisticsTime).Seconds()
+	return strconv.FormatFloat(rateFloat, 'f', 0, 64) + " req/s"
+}
+
+// GetUrlBRate 上次统计之后实际请求接口B的速率，受限流配置影响
+func (s *StatisticsInfo) GetUrlBRate() string {
+	rateFloat := float64(s.urlBRequestCount.Load()-s.lastUrlBRequestCount) / time.Since(s.lastStatisticsTime).Seconds()
+	return strconv.FormatFloat(rateFloat, 'f', 0, 64) + " req/s"
+}
+
 func (s *StatisticsInfo) ResetLastStatisticsInfo() {
 	s.lastStatisticsCount = s.GetProcessedCount()
+	s.lastUrlARequestCount = s.urlARequestCount.Load()
+	s.lastUrlBRequestCount = s.urlBRequestCount.Load()
 	s.lastStatisticsTime = time.Now()
 }
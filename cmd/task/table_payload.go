@@ -0,0 +1,406 @@
+package task
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"http-diff/lib/logger"
+	"http-diff/util"
+
+	"go.uber.org/zap"
+)
+
+// 表格列对应的请求参数
+const (
+	columnTargetParams  = "params"
+	columnTargetHeaders = "headers"
+	columnTargetBody    = "body"
+	columnTargetMethod  = "method"
+	columnTargetPath    = "path"
+)
+
+// 表格列的值类型
+const (
+	columnTypeString = "string"
+	columnTypeNumber = "number"
+	columnTypeBool   = "bool"
+	columnTypeJson   = "json"
+)
+
+// columnMapping 表格列和请求参数的对应关系，格式为 列名:目标[:类型]，列名中的 : 写成 \:
+//
+// 目标为 params.参数名、headers.请求头、body.字段名、body、method、path，body 的字段名可以用 . 表示多级
+type columnMapping struct {
+	column    string
+	target    string
+	fields    []string
+	valueType string
+}
+
+// parseColumnMappings 解析表格列的映射配置
+func parseColumnMappings(mappings []string) ([]*columnMapping, error) {
+	result := make([]*columnMapping, 0, len(mappings))
+	hasBody, hasBodyField := false, false
+
+	for _, value := range mappings {
+		// 列名中的 : 需要写成 \:，多分出一段用于检查格式
+		parts := util.SplitEscaped(value, 4)
+		if len(parts) != 2 && len(parts) != 3 {
+			return nil, errors.New("invalid column mapping format, should be column:target[:type]: " + value)
+		}
+
+		mapping := &columnMapping{column: strings.TrimSpace(parts[0]), valueType: columnTypeString}
+		if mapping.column == "" {
+			return nil, errors.New("column mapping column cannot be empty: " + value)
+		}
+
+		if len(parts) == 3 {
+			mapping.valueType = strings.ToLower(strings.TrimSpace(parts[2]))
+		}
+
+		switch mapping.valueType {
+		case columnTypeString, columnTypeNumber, columnTypeBool, columnTypeJson:
+		default:
+			return nil, errors.New("column mapping type is not supported: " + value)
+		}
+
+		target, field, _ := strings.Cut(strings.TrimSpace(parts[1]), ".")
+		mapping.target = strings.ToLower(target)
+		if field != "" {
+			mapping.fields = strings.Split(field, ".")
+		}
+
+		switch {
+		case mapping.target == columnTargetParams || mapping.target == columnTargetHeaders:
+			if len(mapping.fields) != 1 {
+				return nil, errors.New("column mapping should specify a param or header name: " + value)
+			}
+		case mapping.target == columnTargetBody:
+			if len(mapping.fields) == 0 {
+				hasBody = true
+			} else {
+				hasBodyField = true
+			}
+		case mapping.target == columnTargetMethod || mapping.target == columnTargetPath:
+			if len(mapping.fields) != 0 || mapping.valueType != columnTypeString {
+				return nil, errors.New("column mapping of method and path should be string without field: " + value)
+			}
+		default:
+			return nil, errors.New("column mapping target is not supported: " + value)
+		}
+
+		result = append(result, mapping)
+	}
+
+	if hasBody && hasBodyField {
+		return nil, errors.New("column mapping cannot map both body and body fields")
+	}
+
+	return result, nil
+}
+
+// isTablePayloadFile 是否是 CSV 或 TSV 格式的请求参数文件，压缩文件按照去掉压缩扩展名之后的文件名判断
+func isTablePayloadFile(filePath string) bool {
+	extension := tableFileExtension(filePath)
+	return extension == ".csv" || extension == ".tsv"
+}
+
+func tableFileExtension(filePath string) string {
+	filePath = strings.TrimSuffix(filePath, util.CompressionExtension(util.FileCompression(filePath)))
+	return strings.ToLower(filepath.Ext(filePath))
+}
+
+// newTableReader 创建 CSV 或 TSV 文件的读取器
+func newTableReader(reader io.Reader, filePath string) *csv.Reader {
+	tableReader := csv.NewReader(reader)
+	if tableFileExtension(filePath) == ".tsv" {
+		tableReader.Comma = '\t'
+	}
+	// 表格软件导出的文件中可能包含没有转义的引号，行末的空单元格也可能被省略
+	tableReader.LazyQuotes = true
+	tableReader.FieldsPerRecord = -1
+
+	return tableReader
+}
+
+// tableConverter 把表格的一行转换为请求参数
+type tableConverter struct {
+	mappings []*columnMapping
+	// indexes 每个映射对应的列下标
+	indexes []int
+}
+
+// newTableConverter 根据表头创建转换器，没有配置映射时所有的列都作为同名的 URL 参数
+func newTableConverter(header []string, mappings []*columnMapping) (*tableConverter, error) {
+	defaultMapping := len(mappings) == 0
+	columnIndexes := make(map[string]int, len(header))
+	for index, column := range header {
+		// 表格软件导出的 UTF-8 文件开头可能有 BOM
+		if index == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		column = strings.TrimSpace(column)
+		columnIndexes[column] = index
+
+		if defaultMapping {
+			mappings = append(mappings, &columnMapping{column: column, target: columnTargetParams, fields: []string{column}, valueType: columnTypeString})
+		}
+	}
+
+	converter := &tableConverter{mappings: mappings, indexes: make([]int, 0, len(mappings))}
+	for _, mapping := range mappings {
+		index, ok := columnIndexes[mapping.column]
+		if !ok {
+			return nil, errors.New("column not found in table header: " + mapping.column)
+		}
+		converter.indexes = append(converter.indexes, index)
+	}
+
+	return converter, nil
+}
+
+// convert 把表格的一行转换为请求参数，空的单元格会被忽略
+func (c *tableConverter) convert(record []string) (*Payload, error) {
+	payload := &Payload{}
+	params := make(map[string]interface{})
+	headers := make(map[string]interface{})
+	body := make(map[string]interface{})
+
+	for mappingIndex, mapping := range c.mappings {
+		index := c.indexes[mappingIndex]
+		if index >= len(record) || record[index] == "" {
+			continue
+		}
+
+		cell := record[index]
+		value, err := convertColumnValue(cell, mapping.valueType)
+		if err != nil {
+			return nil, errors.New("invalid value of column " + mapping.column + ": " + err.Error())
+		}
+
+		switch mapping.target {
+		case columnTargetParams:
+			params[mapping.fields[0]] = value
+		case columnTargetHeaders:
+			headers[mapping.fields[0]] = value
+		case columnTargetMethod:
+			payload.Method = strings.ToUpper(cell)
+		case columnTargetPath:
+			payload.Path = cell
+		case columnTargetBody:
+			if len(mapping.fields) > 0 {
+				setBodyField(body, mapping.fields, value)
+				continue
+			}
+
+			if mapping.valueType == columnTypeString {
+				payload.Body = StringValue(cell)
+			} else if err := payload.Body.UnmarshalJSON([]byte(cell)); err != nil {
+				return nil, errors.New("invalid value of column " + mapping.column + ": " + err.Error())
+			}
+		}
+	}
+
+	var err error
+	if len(params) > 0 {
+		if payload.Params, err = JsonValue(params); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(headers) > 0 {
+		if payload.Headers, err = JsonValue(headers); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(body) > 0 {
+		if payload.Body, err = JsonValue(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+// convertColumnValue 按照类型转换单元格的值
+func convertColumnValue(cell string, valueType string) (interface{}, error) {
+	switch valueType {
+	case columnTypeNumber:
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			return nil, errors.New("not a number: " + cell)
+		}
+		return json.Number(cell), nil
+	case columnTypeBool:
+		return strconv.ParseBool(cell)
+	case columnTypeJson:
+		if !json.Valid([]byte(cell)) {
+			return nil, errors.New("not a valid json: " + cell)
+		}
+		return json.RawMessage(cell), nil
+	default:
+		return cell, nil
+	}
+}
+
+// setBodyField 设置请求体中的字段，多级字段不存在时自动创建
+func setBodyField(body map[string]interface{}, fields []string, value interface{}) {
+	for _, field := range fields[:len(fields)-1] {
+		child, ok := body[field].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			body[field] = child
+		}
+		body = child
+	}
+
+	body[fields[len(fields)-1]] = value
+}
+
+// checkTablePayloadFiles 检查表格文件的表头中是否包含映射的列
+func checkTablePayloadFiles(workDir string, files []string, mappings []*columnMapping) error {
+	for _, file := range files {
+		if !isTablePayloadFile(file) {
+			continue
+		}
+
+		header, err := readTableHeader(path.Join(workDir, file))
+		if err != nil {
+			return err
+		}
+
+		if _, err := newTableConverter(header, mappings); err != nil {
+			return errors.New(file + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// readTableHeader 读取表格文件的表头
+func readTableHeader(filePath string) ([]string, error) {
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	header, err := newTableReader(file, filePath).Read()
+	if err != nil {
+		return nil, errors.New("failed to read table header of " + filePath + ": " + err.Error())
+	}
+
+	return header, nil
+}
+
+// countTableRecords 统计表格文件中数据行的数量，不包含表头
+func countTableRecords(filePath string) (int, error) {
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	tableReader := newTableReader(file, filePath)
+	tableReader.ReuseRecord = true
+
+	count := 0
+	for {
+		_, err := tableReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// 格式错误的行和读取时一样计入行数
+		var parseError *csv.ParseError
+		if err != nil && !errors.As(err, &parseError) {
+			return 0, err
+		}
+
+		count++
+	}
+
+	// 第一行是表头
+	if count > 0 {
+		count--
+	}
+
+	return count, nil
+}
+
+// readTablePayloadFile 读取 CSV 或 TSV 格式的请求参数文件并放入通道，第一行为表头，断点中的行号为数据行的序号
+//
+// 返回数据的行数，任务停止时返回 false，文件无法打开或者表头和列映射不匹配时返回错误
+func (t *Task) readTablePayloadFile(fileIndex int, filePath string) (int, bool, error) {
+	processedLine := t.checkpoint.processedLine(fileIndex)
+	logger.Warn(t.ctx, "Task_runReader Reading table file start", zap.String("file", filePath), zap.Int("processedLine", processedLine))
+
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		logger.Error(t.ctx, "Task_runReader Failed to open file", zap.String("filePath", filePath), zap.Error(err))
+		return 0, false, err
+	}
+	defer file.Close()
+
+	tableReader := newTableReader(file, filePath)
+	header, err := tableReader.Read()
+	if err != nil {
+		logger.Error(t.ctx, "Task_runReader Failed to read table header", zap.String("filePath", filePath), zap.Error(err))
+		return 0, false, err
+	}
+
+	converter, err := newTableConverter(header, t.columnMappings)
+	if err != nil {
+		logger.Error(t.ctx, "Task_runReader Failed to create table converter", zap.String("filePath", filePath), zap.Strings("header", header), zap.Error(err))
+		return 0, false, err
+	}
+
+	lineNumber := 0
+	for {
+		record, err := tableReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		// 格式错误的行跳过，读文件出错时停止任务
+		var parseError *csv.ParseError
+		if err != nil && !errors.As(err, &parseError) {
+			logger.Error(t.ctx, "Task_runReader Error reading table file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			return lineNumber, false, err
+		}
+
+		lineNumber++
+		if t.checkpoint.processed(fileIndex, lineNumber) {
+			continue
+		}
+
+		var payload *Payload
+		if err == nil {
+			payload, err = converter.convert(record)
+		}
+
+		if err != nil {
+			t.readFailed(fileIndex, lineNumber, filePath, err)
+			logger.Error(t.ctx, "Task_runReader Failed to parse table row", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Strings("record", record), zap.Error(err))
+			continue
+		}
+
+		payload.fileIndex = fileIndex
+		payload.lineNumber = lineNumber
+
+		if !t.enqueuePayload(payload) {
+			logger.Warn(t.ctx, "Task_runReader Task is shutting down, stop reading payload files", zap.String("file", filePath), zap.Int("lineNumber", lineNumber))
+			return lineNumber, false, nil
+		}
+	}
+
+	logger.Warn(t.ctx, "Task_runReader Reading table file end", zap.String("file", filePath))
+
+	return lineNumber, true, nil
+}
@@ -0,0 +1,261 @@
+package task
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColumnMappings(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []string
+		want     []*columnMapping
+		wantErr  string
+	}{
+		{
+			name:     "targets and types",
+			mappings: []string{"id:params.id", " token : Headers.Authorization ", "age:body.user.age:NUMBER", "url:path", "m:method:string"},
+			want: []*columnMapping{
+				{column: "id", target: columnTargetParams, fields: []string{"id"}, valueType: columnTypeString},
+				{column: "token", target: columnTargetHeaders, fields: []string{"Authorization"}, valueType: columnTypeString},
+				{column: "age", target: columnTargetBody, fields: []string{"user", "age"}, valueType: columnTypeNumber},
+				{column: "url", target: columnTargetPath, valueType: columnTypeString},
+				{column: "m", target: columnTargetMethod, valueType: columnTypeString},
+			},
+		},
+		{
+			name:     "escaped colon in column",
+			mappings: []string{`time\:ms:params.ts:number`},
+			want:     []*columnMapping{{column: "time:ms", target: columnTargetParams, fields: []string{"ts"}, valueType: columnTypeNumber}},
+		},
+		{
+			name:     "whole body",
+			mappings: []string{"data:body:json"},
+			want:     []*columnMapping{{column: "data", target: columnTargetBody, valueType: columnTypeJson}},
+		},
+		{name: "missing target", mappings: []string{"id"}, wantErr: "invalid column mapping format, should be column:target[:type]: id"},
+		{name: "too many parts", mappings: []string{"id:params.id:number:x"}, wantErr: "invalid column mapping format, should be column:target[:type]: id:params.id:number:x"},
+		{name: "empty column", mappings: []string{" :params.id"}, wantErr: "column mapping column cannot be empty:  :params.id"},
+		{name: "unknown type", mappings: []string{"id:params.id:int"}, wantErr: "column mapping type is not supported: id:params.id:int"},
+		{name: "unknown target", mappings: []string{"id:query.id"}, wantErr: "column mapping target is not supported: id:query.id"},
+		{name: "params without name", mappings: []string{"id:params"}, wantErr: "column mapping should specify a param or header name: id:params"},
+		{name: "nested params", mappings: []string{"id:params.a.b"}, wantErr: "column mapping should specify a param or header name: id:params.a.b"},
+		{name: "body and body field", mappings: []string{"data:body:json", "id:body.id"}, wantErr: "column mapping cannot map both body and body fields"},
+		{name: "method with type", mappings: []string{"m:method:json"}, wantErr: "column mapping of method and path should be string without field: m:method:json"},
+		{name: "path with field", mappings: []string{"p:path.a"}, wantErr: "column mapping of method and path should be string without field: p:path.a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, err := parseColumnMappings(tt.mappings)
+			if tt.wantErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, tt.wantErr, err.Error())
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, mappings)
+		})
+	}
+}
+
+func TestTableConverterConvert(t *testing.T) {
+	mappings, err := parseColumnMappings([]string{
+		"id:params.id:number",
+		"vip:params.vip:bool",
+		"token:headers.Authorization",
+		"name:body.user.name",
+		"age:body.user.age:number",
+		"tags:body.tags:json",
+		"method:method",
+		"url:path",
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// 表格软件导出的文件开头的 BOM 会被忽略
+	converter, err := newTableConverter([]string{"\ufeffid", "vip", "token", "name", "age", "tags", "method", "url"}, mappings)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	payload, err := converter.convert([]string{"12", "true", "abc", "tom", "18.5", `["a",1]`, "put", "/user/12"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.JSONEq(t, `{"id":12,"vip":true}`, payload.Params.String())
+	assert.JSONEq(t, `{"Authorization":"abc"}`, payload.Headers.String())
+	assert.JSONEq(t, `{"user":{"name":"tom","age":18.5},"tags":["a",1]}`, payload.Body.String())
+	assert.Equal(t, "PUT", payload.Method)
+	assert.Equal(t, "/user/12", payload.Path)
+
+	// 空的单元格和缺少的单元格会被忽略
+	payload, err = converter.convert([]string{"", "", "abc", "", "18"})
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, payload.Params.IsEmpty())
+	assert.JSONEq(t, `{"user":{"age":18}}`, payload.Body.String())
+	assert.Equal(t, "", payload.Method)
+	assert.Equal(t, "", payload.Path)
+
+	tests := []struct {
+		name    string
+		record  []string
+		wantErr string
+	}{
+		{name: "number", record: []string{"abc"}, wantErr: "invalid value of column id: not a number: abc"},
+		{name: "bool", record: []string{"1", "yes"}, wantErr: `invalid value of column vip: strconv.ParseBool: parsing "yes": invalid syntax`},
+		{name: "json", record: []string{"1", "true", "", "", "", "[1,"}, wantErr: "invalid value of column tags: not a valid json: [1,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := converter.convert(tt.record)
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestTableConverterBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapping   string
+		cell      string
+		wantJson  bool
+		wantValue string
+	}{
+		{name: "string", mapping: "data:body", cell: `{"a":1}`, wantValue: `{"a":1}`},
+		{name: "json", mapping: "data:body:json", cell: `{ "a" : 1 }`, wantJson: true, wantValue: `{"a":1}`},
+		{name: "number", mapping: "data:body:number", cell: "5", wantJson: true, wantValue: "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mappings, err := parseColumnMappings([]string{tt.mapping})
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			converter, err := newTableConverter([]string{"data"}, mappings)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			payload, err := converter.convert([]string{tt.cell})
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantJson, payload.Body.IsJson())
+			assert.Equal(t, tt.wantValue, payload.Body.String())
+		})
+	}
+}
+
+func TestNewTableConverter(t *testing.T) {
+	// 没有配置映射时所有的列作为同名的 URL 参数
+	converter, err := newTableConverter([]string{"\ufeffid", " name "}, nil)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	payload, err := converter.convert([]string{"1", "tom"})
+	if assert.Nil(t, err) {
+		assert.JSONEq(t, `{"id":"1","name":"tom"}`, payload.Params.String())
+	}
+
+	mappings, err := parseColumnMappings([]string{"uid:params.id"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	_, err = newTableConverter([]string{"id"}, mappings)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "column not found in table header: uid", err.Error())
+	}
+}
+
+func TestTablePayloadFileExtension(t *testing.T) {
+	tests := []struct {
+		file      string
+		isTable   bool
+		separator rune
+	}{
+		{file: "payload.csv", isTable: true, separator: ','},
+		{file: "payload.CSV.gz", isTable: true, separator: ','},
+		{file: "payload.tsv", isTable: true, separator: '\t'},
+		{file: "payload.tsv.gz", isTable: true, separator: '\t'},
+		{file: "payload.TSV.zst", isTable: true, separator: '\t'},
+		{file: "payload.txt", separator: ','},
+		{file: "payload.txt.gz", separator: ','},
+		{file: "payload.gz", separator: ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.isTable, isTablePayloadFile(tt.file))
+			assert.Equal(t, tt.separator, newTableReader(strings.NewReader(""), tt.file).Comma)
+		})
+	}
+}
+
+func TestReadTablePayloadFile(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		body, _ := json.Marshal(map[string]string{"id": request.URL.Query().Get("id"), "name": request.URL.Query().Get("name")})
+		_, _ = writer.Write(body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		file  string
+		comma string
+	}{
+		{name: "csv", file: "payload.csv", comma: ","},
+		{name: "tsv gzip", file: "payload.tsv.gz", comma: "\t"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+
+			// 带引号的单元格中包含换行，数据行的序号和文件的行号不同，第 3 行数据格式错误
+			lines := []string{
+				"\ufeffid" + tt.comma + "name",
+				"1" + tt.comma + "tom",
+				"2" + tt.comma + "\"multi\nline\"",
+				"abc" + tt.comma + "bad",
+				"4" + tt.comma,
+			}
+			writeCompressedTestFile(t, path.Join(workDir, tt.file), lines...)
+
+			count, err := countTableRecords(path.Join(workDir, tt.file))
+			assert.Nil(t, err)
+			assert.Equal(t, 4, count)
+
+			cfg := newTestConfig("table", workDir, tt.file)
+			cfg.ColumnMapping = []string{"id:params.id:number", "name:params.name"}
+			cfg.UrlA = server.URL + "/a"
+			cfg.UrlB = server.URL + "/b"
+			task := runTestTask(t, cfg)
+
+			assert.Equal(t, int64(4), task.statisticsInfo.GetTotalCount())
+			assert.Equal(t, int64(3), task.statisticsInfo.GetSameCount())
+			assert.Equal(t, int64(1), task.statisticsInfo.GetFailedCount())
+			assert.Equal(t, 4, task.checkpoint.processedLine(0))
+			assert.Equal(t, []string{tt.file + ":3: invalid value of column id: not a number: abc"}, task.recentErrors.list())
+		})
+	}
+}
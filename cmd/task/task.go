@@ -4,15 +4,20 @@ import (
 	"bufio"
 	"context"
 	"errors"
-	"os"
+	"io"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"http-diff/constant"
 	"http-diff/lib/concurrency"
 	"http-diff/lib/logger"
+	"http-diff/lib/ratelimit"
+	"http-diff/lib/rewrite"
 	"http-diff/lib/safe"
+	"http-diff/lib/variable"
 	"http-diff/util"
 
 	"github.com/bytedance/sonic"
@@ -27,28 +32,71 @@ type Task struct {
 	stopCh chan struct{}
 	// stopChOnce 用于确保 stopCh 只被关闭一次
 	stopChOnce *sync.Once
+	// shutdownCh 用于通知任务停止读取和处理新的请求参数，已经在处理的请求参数会继续处理完成
+	shutdownCh chan struct{}
+	// shutdownChOnce 用于确保 shutdownCh 只被关闭一次
+	shutdownChOnce *sync.Once
 
 	// Config 任务配置
 	Config Config
 	// SuccessConditionMap 接口响应成功的条件
 	SuccessConditionMap map[string]string
+	// UnorderedArrayRules 无序数组规则
+	UnorderedArrayRules []util.UnorderedArrayRule
+	// cmpOptions 对比响应数据时使用的选项
+	cmpOptions []cmp.Option
 
 	// waitGroup 用户等待任务的子程序结束
 	waitGroup *sync.WaitGroup
+	// writerWaitGroup 用于等待写文件的子程序把剩余的数据写入文件
+	writerWaitGroup *sync.WaitGroup
 	// statisticsInfo 任务统计信息
 	statisticsInfo *StatisticsInfo
+	// reportCollector 汇总对比结果，任务结束时生成差异报告
+	reportCollector *reportCollector
+	// recentErrors 最近的错误信息，用于在控制台展示
+	recentErrors *recentErrors
+	// checkpoint 记录请求参数的处理进度，用于断点续跑
+	checkpoint *checkpointTracker
+	// resultFiles 打开的结果文件，保存断点之前刷新
+	resultFiles     []*resultFile
+	resultFilesLock sync.Mutex
+	// resultLock 写结果文件和更新断点时加读锁，保存断点时加写锁，保证断点中的进度和结果文件的行数一致
+	resultLock sync.RWMutex
+	// payloadFiles 展开 glob 模式之后的请求参数文件，相对于工作目录
+	payloadFiles []string
+	// columnMappings CSV 和 TSV 格式的请求参数文件的列映射，为空时所有列作为 URL 参数
+	columnMappings []*columnMapping
+	// variables 模板变量，没有配置变量时为 nil
+	variables *variable.Set
+	// payloadTemplate 请求参数模板，为 nil 时读取 payload 文件
+	payloadTemplate *variable.Template
 
 	// UrlAInfo 接口A请求信息
 	UrlAInfo *Info
 	// UrlBInfo 接口B请求信息
 	UrlBInfo *Info
 
+	// limiter 任务的限流器，所有并发共享，为 nil 时不限流
+	limiter *ratelimit.Limiter
+	// urlALimiter 接口A的限流器，为 nil 时不限流
+	urlALimiter *ratelimit.Limiter
+	// urlBLimiter 接口B的限流器，为 nil 时不限流
+	urlBLimiter *ratelimit.Limiter
+	// adaptiveLimiter 自适应并发限制器，为 nil 时使用固定的并发数
+	adaptiveLimiter *concurrency.AdaptiveLimiter
+
 	// inputCh 输入通道，用于接收待处理的 Payload
 	inputCh chan *Payload
 	// outputCh 输出通道，用于发送处理结果
 	outputCh chan *OutPut
 	// failedCH 错误输出通道，用于发送处理错误信息
 	failedCH chan *FailedOutPut
+	// recordCh 录制通道，录制模式下用于发送录制结果
+	recordCh chan *Record
+
+	// records 录制的原始响应数据，回放模式下每次反序列化之后作为接口A的响应
+	records map[string][]byte
 }
 
 type Config struct {
@@ -56,23 +104,85 @@ type Config struct {
 	TaskName string
 	// WorkDir 工作目录
 	WorkDir string
-	// Payload 文件路径或内容
+	// Payload 文件路径或内容，支持 glob 模式
 	Payload string
+	// ColumnMapping CSV 和 TSV 格式的请求参数文件的列映射，格式为 列名:目标[:类型]
+	ColumnMapping []string
+	// PayloadTemplate 请求参数模板，设置后根据迭代变量生成请求参数，不再读取 payload 文件
+	PayloadTemplate string
+	// Variables 模板变量，格式为 名称:类型[:参数]
+	Variables []string
+	// CompressOutput 对比结果和错误数据文件的压缩格式 gzip、zstd，为空时不压缩
+	CompressOutput string
+	// InputBufferSize 请求参数通道的缓冲区大小
+	InputBufferSize int
+	// OutputBufferSize 对比结果、录制数据和错误数据通道的缓冲区大小
+	OutputBufferSize int
 	// WaitTime 等待时间
 	WaitTime time.Duration
-
-	// Concurrency 并发数
+	// QPS 任务每秒最多处理的请求数量，小于等于 0 表示不限制
+	QPS float64
+	// Burst 任务允许的最大突发请求数量
+	Burst int
+	// UrlAQPS 每秒最多请求接口A的次数，小于等于 0 表示不限制
+	UrlAQPS float64
+	// UrlABurst 请求接口A允许的最大突发请求数量
+	UrlABurst int
+	// UrlBQPS 每秒最多请求接口B的次数，小于等于 0 表示不限制
+	UrlBQPS float64
+	// UrlBBurst 请求接口B允许的最大突发请求数量
+	UrlBBurst int
+
+	// Concurrency 并发数，开启自适应并发时为最大并发数
 	Concurrency int
+	// AdaptiveConcurrency 是否根据错误率和耗时自动调整并发数
+	AdaptiveConcurrency bool
+	// MinConcurrency 自适应并发的最小并发数，也是初始并发数
+	MinConcurrency int
+	// AdaptiveMaxErrorRate 自适应并发的错误率阈值
+	AdaptiveMaxErrorRate float64
+	// AdaptiveMaxLatency 自适应并发的 p95 耗时阈值
+	AdaptiveMaxLatency time.Duration
+	// AdaptiveInterval 自适应并发的调整周期
+	AdaptiveInterval time.Duration
+	// Resume 是否从上次保存的断点继续运行
+	Resume bool
+	// Mode 运行模式 diff、record、replay
+	Mode string
+	// RecordFile 录制文件
+	RecordFile string
 	// UrlA 接口A地址
 	UrlA string
 	// UrlB 接口B地址
 	UrlB string
+	// UrlARewrites 请求接口A之前的改写规则
+	UrlARewrites []string
+	// UrlBRewrites 请求接口B之前的改写规则
+	UrlBRewrites []string
 	// Method HTTP方法
 	Method string
 	// ContentType 内容类型
 	ContentType string
+	// ResponseFormat 响应数据格式
+	ResponseFormat string
+	// CompareStatusCode 是否对比状态码
+	CompareStatusCode bool
+	// CompareHeaders 需要对比的响应头
+	CompareHeaders []string
 	// IgnoreFields 忽略的字段
 	IgnoreFields []string
+	// UnorderedArrays 无序数组，格式为 路径:key，多个用逗号分隔
+	UnorderedArrays []string
+	// FloatTolerance 数字的绝对容差
+	FloatTolerance float64
+	// FloatRelativeTolerance 数字的相对容差
+	FloatRelativeTolerance float64
+	// FloatPathTolerances 指定路径的数字容差，格式为 路径:绝对容差:相对容差
+	FloatPathTolerances []string
+	// StringNumberEqual 字符串和数字表示相同的值时是否认为相等
+	StringNumberEqual bool
+	// LatencyRatio 接口B的耗时超过接口A的耗时乘以该比例时记为性能差异，小于等于 0 时不检查
+	LatencyRatio float64
 	// OutputShowNoDiffLine 是否输出没有差异的行
 	OutputShowNoDiffLine bool
 	// LogStatistics 是在日志中录统计信息
@@ -82,18 +192,49 @@ type Config struct {
 }
 
 func InitTask(ctx context.Context, cfg Config) (*Task, error) {
+	// 没有配置响应数据格式时按照 JSON 处理，之后只需要判断是否为 json
+	if cfg.ResponseFormat == "" {
+		cfg.ResponseFormat = constant.ResponseFormatJson
+	}
 
-	files := strings.Split(cfg.Payload, ",")
-	lineCount := 0
-	for _, file := range files {
-		filePath := path.Join(cfg.WorkDir, file)
+	variables, payloadTemplate, err := initPayloadTemplate(cfg)
+	if err != nil {
+		logger.Error(ctx, "InitTask Failed to parse payload template", zap.Strings("variables", cfg.Variables), zap.String("payloadTemplate", cfg.PayloadTemplate), zap.Error(err))
+		return nil, err
+	}
+
+	columnMappings, err := parseColumnMappings(cfg.ColumnMapping)
+	if err != nil {
+		logger.Error(ctx, "InitTask Invalid column mapping format", zap.Strings("columnMapping", cfg.ColumnMapping), zap.Error(err))
+		return nil, err
+	}
 
-		count, err := util.FileLineCount(filePath)
+	// 使用请求参数模板时没有 payload 文件，断点中按照生成的顺序记录进度
+	files := []string{templateCheckpointFile}
+	if payloadTemplate == nil {
+		// 只检查文件是否存在，总行数在任务运行时统计
+		files, err = expandPayloadFiles(cfg.WorkDir, cfg.Payload)
 		if err != nil {
+			logger.Error(ctx, "InitTask Failed to expand payload files", zap.String("payload", cfg.Payload), zap.Error(err))
+			return nil, err
+		}
+
+		if err := checkTablePayloadFiles(cfg.WorkDir, files, columnMappings); err != nil {
+			logger.Error(ctx, "InitTask Invalid table payload file", zap.Strings("files", files), zap.Error(err))
 			return nil, err
 		}
+	}
+
+	urlARewrite, err := rewrite.Parse(cfg.UrlARewrites)
+	if err != nil {
+		logger.Error(ctx, "InitTask Invalid url_a rewrite rules", zap.Strings("urlARewrites", cfg.UrlARewrites), zap.Error(err))
+		return nil, err
+	}
 
-		lineCount = lineCount + count
+	urlBRewrite, err := rewrite.Parse(cfg.UrlBRewrites)
+	if err != nil {
+		logger.Error(ctx, "InitTask Invalid url_b rewrite rules", zap.Strings("urlBRewrites", cfg.UrlBRewrites), zap.Error(err))
+		return nil, err
 	}
 
 	task := &Task{
@@ -101,23 +242,47 @@ func InitTask(ctx context.Context, cfg Config) (*Task, error) {
 		stopCh:     make(chan struct{}),
 		stopChOnce: &sync.Once{},
 
+		shutdownCh:     make(chan struct{}),
+		shutdownChOnce: &sync.Once{},
+
 		Config:              cfg,
 		SuccessConditionMap: make(map[string]string),
 		waitGroup:           &sync.WaitGroup{},
-		statisticsInfo:      NewStatisticsInfo(lineCount),
+		writerWaitGroup:     &sync.WaitGroup{},
+		statisticsInfo:      NewStatisticsInfo(),
+		reportCollector:     newReportCollector(),
+		recentErrors:        newRecentErrors(),
+		checkpoint:          newCheckpointTracker(files),
+		payloadFiles:        files,
+		columnMappings:      columnMappings,
+		variables:           variables,
+		payloadTemplate:     payloadTemplate,
 		UrlAInfo: &Info{
-			Method:      cfg.Method,
-			Url:         cfg.UrlA,
-			ContentType: cfg.ContentType,
+			Method:            cfg.Method,
+			Url:               cfg.UrlA,
+			ContentType:       cfg.ContentType,
+			ResponseFormat:    cfg.ResponseFormat,
+			CompareStatusCode: cfg.CompareStatusCode,
+			CompareHeaders:    cfg.CompareHeaders,
+			Rewrite:           urlARewrite,
 		},
 		UrlBInfo: &Info{
-			Method:      cfg.Method,
-			Url:         cfg.UrlB,
-			ContentType: cfg.ContentType,
+			Method:            cfg.Method,
+			Url:               cfg.UrlB,
+			ContentType:       cfg.ContentType,
+			ResponseFormat:    cfg.ResponseFormat,
+			CompareStatusCode: cfg.CompareStatusCode,
+			CompareHeaders:    cfg.CompareHeaders,
+			Rewrite:           urlBRewrite,
 		},
-		inputCh:  make(chan *Payload, 100000),
-		outputCh: make(chan *OutPut, 100000),
-		failedCH: make(chan *FailedOutPut, 10000),
+		limiter:     ratelimit.NewLimiter(cfg.QPS, cfg.Burst),
+		urlALimiter: ratelimit.NewLimiter(cfg.UrlAQPS, cfg.UrlABurst),
+		urlBLimiter: ratelimit.NewLimiter(cfg.UrlBQPS, cfg.UrlBBurst),
+		inputCh:     make(chan *Payload, cfg.InputBufferSize),
+		outputCh:    make(chan *OutPut, cfg.OutputBufferSize),
+		failedCH:    make(chan *FailedOutPut, cfg.OutputBufferSize),
+		recordCh:    make(chan *Record, cfg.OutputBufferSize),
+		records:     make(map[string][]byte),
 	}
 
 	if cfg.SuccessConditions != "" {
@@ -133,122 +298,324 @@ func InitTask(ctx context.Context, cfg Config) (*Task, error) {
 		}
 	}
 
+	for _, unorderedArray := range cfg.UnorderedArrays {
+		rule, err := parseUnorderedArrayRule(unorderedArray)
+		if err != nil {
+			logger.Error(ctx, "InitTask Invalid unordered array format", zap.String("unorderedArray", unorderedArray), zap.Error(err))
+			return nil, err
+		}
+		task.UnorderedArrayRules = append(task.UnorderedArrayRules, rule)
+	}
+
+	compareOptions := util.CompareOptions{
+		Tolerance:         util.NumericTolerance{Absolute: cfg.FloatTolerance, Relative: cfg.FloatRelativeTolerance},
+		StringNumberEqual: cfg.StringNumberEqual,
+	}
+	for _, pathTolerance := range cfg.FloatPathTolerances {
+		tolerance, err := parsePathTolerance(pathTolerance)
+		if err != nil {
+			logger.Error(ctx, "InitTask Invalid float path tolerance format", zap.String("pathTolerance", pathTolerance), zap.Error(err))
+			return nil, err
+		}
+		compareOptions.PathTolerances = append(compareOptions.PathTolerances, tolerance)
+	}
+
+	cmpOptions, err := util.NewCmpOptions(compareOptions)
+	if err != nil {
+		logger.Error(ctx, "InitTask Failed to create compare options", zap.Any("compareOptions", compareOptions), zap.Error(err))
+		return nil, err
+	}
+	task.cmpOptions = cmpOptions
+
+	if cfg.AdaptiveConcurrency {
+		task.adaptiveLimiter = concurrency.NewAdaptiveLimiter(concurrency.AdaptiveConfig{
+			Min:          cfg.MinConcurrency,
+			Max:          cfg.Concurrency,
+			MaxErrorRate: cfg.AdaptiveMaxErrorRate,
+			MaxLatency:   cfg.AdaptiveMaxLatency,
+		})
+	}
+
+	if cfg.Mode == constant.ModeReplay {
+		if err := task.loadRecord(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Resume {
+		if err := task.loadCheckpoint(); err != nil {
+			return nil, err
+		}
+
+		if err := task.restoreResultFiles(); err != nil {
+			return nil, err
+		}
+
+		if cfg.Mode != constant.ModeRecord {
+			if err := task.restoreReport(); err != nil {
+				logger.Error(ctx, "InitTask Failed to restore report", zap.String("task", cfg.TaskName), zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+
+	task.registerMetrics()
+
 	return task, nil
 }
 
 func (t *Task) Run() {
 	logger.Info(t.ctx, "Task_Run Start running task", zap.Any("task", t))
 
-	// 读文件
-	go safe.RecoveryWithLoggerAndCallback(t.runReader, t.ctx, "Task_Run_runReader", func() { t.stop() })
-	time.Sleep(time.Second * 2) // 等待文件读取完成，避免在文件读取过程中就开始处理请求
+	if t.payloadTemplate != nil {
+		// 模板生成的请求参数数量是确定的，生成过程中计入 waitGroup
+		t.statisticsInfo.SetTotalCount(t.variables.Count())
+		t.waitGroup.Add(1)
+		go safe.RecoveryWithLoggerAndCallback(t.runGenerator, t.ctx, "Task_Run_runGenerator", func() { t.stop() })
+	} else {
+		// 统计请求参数总行数，和读文件同时进行
+		go safe.RecoveryWithLogger(t.countPayloadLines, t.ctx, "Task_Run_countPayloadLines")
+
+		// 读文件，读取过程中计入 waitGroup，避免还没有读到请求参数时就判断任务已经完成
+		t.waitGroup.Add(1)
+		go safe.RecoveryWithLoggerAndCallback(t.runReader, t.ctx, "Task_Run_runReader", func() { t.stop() })
+	}
 
-	// 处理请求
+	// 处理请求，开启自适应并发时只有编号小于当前并发数的协程会处理请求
 	for i := 0; i < t.Config.Concurrency; i++ {
-		go safe.RecoveryWithLoggerAndCallback(t.run, t.ctx, "Task_Run_run", func() { t.stop() })
+		index := i
+		go safe.RecoveryWithLoggerAndCallback(func() { t.run(index) }, t.ctx, "Task_Run_run", func() { t.stop() })
 	}
 
-	// 写结果
-	go safe.RecoveryWithLoggerAndCallback(func() {
-		if err := t.writeOutputToFile(); err != nil {
-			t.stop()
-		}
-	}, t.ctx, "Task_Run_writeOutputToFile", func() { t.stop() })
+	if t.adaptiveLimiter != nil {
+		go safe.RecoveryWithLogger(t.adjustConcurrencyLoop, t.ctx, "Task_Run_adjustConcurrencyLoop")
+	}
+
+	// 写结果，录制模式下写录制文件
+	if t.Config.Mode == constant.ModeRecord {
+		t.startWriter(t.writeRecordToFile, "Task_Run_writeRecordToFile")
+	} else {
+		t.startWriter(t.writeOutputToFile, "Task_Run_writeOutputToFile")
+	}
 
 	// 写错误数据
-	go safe.RecoveryWithLoggerAndCallback(func() {
-		if err := t.writeFailedPayloadToFile(); err != nil {
-			t.stop()
-		}
-	}, t.ctx, "Task_Run_writeFailedPayloadToFile", func() { t.stop() })
+	t.startWriter(t.writeFailedPayloadToFile, "Task_Run_writeFailedPayloadToFile")
 
 	// 记录统计信息
 	if t.Config.LogStatistics {
 		go safe.RecoveryWithLoggerAndCallback(t.logStatisticsInfoLoop, t.ctx, "Task_Run_logStatisticsInfoLoop", func() { t.stop() })
 	}
 
+	// 定时保存断点
+	go safe.RecoveryWithLogger(t.saveCheckpointLoop, t.ctx, "Task_Run_saveCheckpointLoop")
+
 	// 等代任务运行完成
 	go func() {
 		t.waitGroup.Wait()
 		t.stop()
 	}()
 
+	// 上下文取消时强制结束任务，正在处理的请求参数不再等待
+	go func() {
+		select {
+		case <-t.ctx.Done():
+			t.stop()
+		case <-t.Done():
+		}
+	}()
+
 	// 等待任务结束信号
 	<-t.Done()
 
+	// 等待通道中剩余的数据写入文件之后再保存断点
+	t.writerWaitGroup.Wait()
+	t.saveCheckpoint()
+
 	//任务运行结束的时候打印一次日志
 	if t.Config.LogStatistics {
 		t.logStatisticsInfo()
 	}
 
+	// 生成差异汇总报告，录制模式下没有对比结果
+	if t.Config.Mode != constant.ModeRecord {
+		t.writeReportToFile()
+	}
+
 	logger.Info(t.ctx, "Task_Run Stop running task", zap.Any("task", t))
 }
 
 func (t *Task) runReader() {
-	payLoadFiles := strings.Split(t.Config.Payload, ",")
+	defer t.waitGroup.Done()
+
+	payLoadFiles := t.payloadFiles
+	totalLines := 0
 
 	logger.Info(t.ctx, "Task_runReader Starting to read payload files", zap.Strings("files", payLoadFiles))
 
-	for _, payLoadFile := range payLoadFiles {
-		filePath := path.Join(t.Config.WorkDir, payLoadFile)
-		logger.Warn(t.ctx, "Task_runReader Reading file start", zap.String("file", filePath))
+	for fileIndex, payLoadFile := range payLoadFiles {
+		readFile := t.readPayloadFile
+		if isTablePayloadFile(payLoadFile) {
+			readFile = t.readTablePayloadFile
+		}
 
-		file, err := os.Open(filePath)
+		lineCount, finished, err := readFile(fileIndex, path.Join(t.Config.WorkDir, payLoadFile))
 		if err != nil {
-			logger.Error(t.ctx, "Task_runReader Failed to open file", zap.String("filePath", filePath), zap.Error(err))
-			panic(err)
+			// 文件无法读取时停止任务，已经放入通道的请求参数不再处理，断点续跑时重新处理
+			logger.Error(t.ctx, "Task_runReader Failed to read payload file, stop task", zap.String("file", payLoadFile), zap.Error(err))
+			t.recentErrors.add(payLoadFile + ": " + err.Error())
+			t.stop()
+			return
 		}
 
-		maxLineSize := 1024 * 1024
-		buffer := make([]byte, 0, maxLineSize)
-		scanner := bufio.NewScanner(file)
-		scanner.Buffer(buffer, maxLineSize)
-		lineNumber := 0
+		if !finished {
+			return
+		}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			lineNumber++
+		totalLines += lineCount
+	}
 
-			logger.Debug(t.ctx, "Task_runReader Read line from file", zap.String("line", line), zap.Int("lineNumber", lineNumber))
+	// 读完所有文件之后总行数是准确的，覆盖统计的结果
+	t.statisticsInfo.SetTotalCount(int64(totalLines))
 
-			if len(line) == 0 {
-				t.statisticsInfo.AddFailed()
-				logger.Error(t.ctx, "Task_runReader Empty line in file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber))
-				continue
-			}
+	logger.Info(t.ctx, "Task_runReader Finished reading all payload files", zap.Any("files", payLoadFiles))
+}
 
-			if err := scanner.Err(); err != nil {
-				t.statisticsInfo.AddFailed()
-				logger.Error(t.ctx, "Task_runReader Error reading file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
-				continue
-			}
+// readPayloadFile 读取请求参数文件并放入通道，返回文件的行数，任务停止时返回 false，文件无法打开时返回错误
+func (t *Task) readPayloadFile(fileIndex int, filePath string) (int, bool, error) {
+	// 断点续跑时跳过已经处理完成的行
+	processedLine := t.checkpoint.processedLine(fileIndex)
+	logger.Warn(t.ctx, "Task_runReader Reading file start", zap.String("file", filePath), zap.Int("processedLine", processedLine))
 
-			payload := &Payload{}
-			err := sonic.Unmarshal([]byte(line), payload)
-			if err != nil {
-				t.statisticsInfo.AddFailed()
-				logger.Error(t.ctx, "Task_runReader Failed to unmarshal payload", zap.String("line", line), zap.Int("lineNumber", lineNumber), zap.Error(err))
-				continue
+	file, err := util.OpenReader(filePath)
+	if err != nil {
+		logger.Error(t.ctx, "Task_runReader Failed to open file", zap.String("filePath", filePath), zap.Error(err))
+		return 0, false, err
+	}
+	defer file.Close()
+
+	maxLineSize := 1024 * 1024
+	buffer := make([]byte, 0, maxLineSize)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(buffer, maxLineSize)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+
+		if t.checkpoint.processed(fileIndex, lineNumber) {
+			continue
+		}
+
+		logger.Debug(t.ctx, "Task_runReader Read line from file", zap.String("line", line), zap.Int("lineNumber", lineNumber))
+
+		if len(line) == 0 {
+			t.readFailed(fileIndex, lineNumber, filePath, errors.New("empty line"))
+			logger.Error(t.ctx, "Task_runReader Empty line in file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber))
+			continue
+		}
+
+		if err := scanner.Err(); err != nil {
+			t.readFailed(fileIndex, lineNumber, filePath, err)
+			logger.Error(t.ctx, "Task_runReader Error reading file", zap.String("filePath", filePath), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			continue
+		}
+
+		payload := &Payload{}
+		if t.variables != nil && variable.IsTemplate(line) {
+			// 包含变量的行在请求之前替换变量
+			payload.template, err = t.parseLineTemplate(line)
+		} else {
+			err = sonic.Unmarshal([]byte(line), payload)
+			if err == nil {
+				err = checkPayload(payload)
 			}
+		}
+
+		if err != nil {
+			t.readFailed(fileIndex, lineNumber, filePath, err)
+			logger.Error(t.ctx, "Task_runReader Failed to parse payload", zap.String("line", line), zap.Int("lineNumber", lineNumber), zap.Error(err))
+			continue
+		}
 
-			t.waitGroup.Add(1)
+		payload.fileIndex = fileIndex
+		payload.lineNumber = lineNumber
 
-			logger.Debug(t.ctx, "Task_runReader Adding payload to input channel", zap.Any("payload", payload))
-			t.inputCh <- payload
+		if !t.enqueuePayload(payload) {
+			logger.Warn(t.ctx, "Task_runReader Task is shutting down, stop reading payload files", zap.String("file", filePath), zap.Int("lineNumber", lineNumber))
+			return lineNumber, false, nil
 		}
+	}
+
+	logger.Warn(t.ctx, "Task_runReader Reading file end", zap.String("file", filePath))
 
-		logger.Warn(t.ctx, "Task_runReader Reading file end", zap.String("file", filePath))
+	return lineNumber, true, nil
+}
+
+// readFailed 记录读取时无法解析的行，计入失败数量和最近的错误信息，并更新断点
+func (t *Task) readFailed(fileIndex int, lineNumber int, filePath string, err error) {
+	t.statisticsInfo.AddFailed()
+	t.checkpoint.done(fileIndex, lineNumber, resultFailed)
+	t.recentErrors.add(path.Base(filePath) + ":" + strconv.Itoa(lineNumber) + ": " + err.Error())
+}
+
+// enqueuePayload 把请求参数放入通道，放入之前计入 waitGroup，任务停止时返回 false
+func (t *Task) enqueuePayload(payload *Payload) bool {
+	if t.isShutdown() {
+		return false
 	}
 
-	logger.Info(t.ctx, "Task_runReader Finished reading all payload files", zap.Any("files", payLoadFiles))
+	t.waitGroup.Add(1)
+
+	logger.Debug(t.ctx, "Task_enqueuePayload Adding payload to input channel", zap.Any("payload", payload))
+	select {
+	case t.inputCh <- payload:
+		return true
+	case <-t.shutdownCh:
+		t.waitGroup.Done()
+		return false
+	}
 }
 
-func (t *Task) run() {
+// countPayloadLines 统计所有请求参数文件的总行数，读文件先完成时不再设置
+func (t *Task) countPayloadLines() {
+	totalLines := 0
+	for _, payLoadFile := range t.payloadFiles {
+		filePath := path.Join(t.Config.WorkDir, payLoadFile)
+
+		// 表格文件中带引号的单元格可以包含换行，按照数据行统计
+		countLines := util.FileLineCount
+		if isTablePayloadFile(payLoadFile) {
+			countLines = countTableRecords
+		}
+
+		count, err := countLines(filePath)
+		if err != nil {
+			logger.Error(t.ctx, "Task_countPayloadLines Failed to count file lines", zap.String("filePath", filePath), zap.Error(err))
+			return
+		}
+
+		totalLines += count
+	}
+
+	if t.statisticsInfo.SetTotalCountIfUnknown(int64(totalLines)) {
+		logger.Info(t.ctx, "Task_countPayloadLines Finished counting payload lines", zap.String("task", t.Config.TaskName), zap.Int("totalLines", totalLines))
+	}
+}
+
+func (t *Task) run(index int) {
 	for {
+		// 停止时不再处理新的请求参数，通道中剩余的请求参数会被丢弃，断点续跑时重新处理
+		if t.isShutdown() || !t.waitActive(index) {
+			return
+		}
+
 	SelectLoop:
 		select {
 		case <-t.ctx.Done():
 			return
+		case <-t.shutdownCh:
+			return
 		case payload := <-t.inputCh:
 			logger.Debug(t.ctx, "Task_run Processing payload", zap.String("task", t.Config.TaskName), zap.Any("payload", payload))
 
@@ -257,21 +624,39 @@ func (t *Task) run() {
 				time.Sleep(t.Config.WaitTime)
 			}
 
-			var urlAResponse interface{}
+			// 上下文取消时任务会被强制结束，不再处理当前的请求参数
+			if err := t.limiter.Wait(t.ctx); err != nil {
+				return
+			}
+
+			// 限流之后再替换变量，保证当前时间等变量的值是请求时生成的
+			if err := renderPayload(t.variables, payload); err != nil {
+				logger.Error(t.ctx, "Task_run Failed to render payload template", zap.Any("payload", payload), zap.Error(err))
+				t.statisticsInfo.AddFailed()
+				t.failedCH <- NewFailedOutput(payload, err)
+				break SelectLoop
+			}
+
+			if t.Config.Mode == constant.ModeRecord {
+				t.record(payload)
+				break SelectLoop
+			}
+
+			var urlAResponse *Response
 			var urlAResponseErr error
-			var urlBResponse interface{}
+			var urlBResponse *Response
 			var urlBResponseErr error
 
 			safeGoWaitGroup := concurrency.NewSafeGoWaitGroup()
 			safeGoWaitGroup.SafeGoWithLogger(func() {
-				urlAResponse, urlAResponseErr = DoRequest(t.ctx, t.UrlAInfo, payload)
+				urlAResponse, urlAResponseErr = t.requestUrlA(payload)
 			}, func(message any) {
 				logger.Error(t.ctx, "Task_run Failed to get response from UrlA", zap.Any("urlA", t.UrlAInfo), zap.Any("payload", payload), zap.Any("message", message))
 				urlAResponseErr = errors.New("failed to get response from UrlA: " + cast.ToString(message))
 			})
 
 			safeGoWaitGroup.SafeGoWithLogger(func() {
-				urlBResponse, urlBResponseErr = DoRequest(t.ctx, t.UrlBInfo, payload)
+				urlBResponse, urlBResponseErr = t.requestUrlB(payload)
 			}, func(message any) {
 				logger.Error(t.ctx, "Task_run Failed to get response from UrlB", zap.Any("urlB", t.UrlBInfo), zap.Any("payload", payload), zap.Any("message", message))
 				urlBResponseErr = errors.New("failed to get response from UrlB: " + cast.ToString(message))
@@ -285,75 +670,145 @@ func (t *Task) run() {
 				break SelectLoop
 			}
 
-			if !t.responseSuccess(urlAResponse) || !t.responseSuccess(urlBResponse) {
-				logger.Error(t.ctx, "Task_run Response does not meet success conditions", zap.Any("payload", payload), zap.Any("urlAResponse", urlAResponse), zap.Any("urlBResponse", urlBResponse))
-				t.failedCH <- NewFailedOutput(payload, errors.New("response does not meet success conditions"))
-				t.statisticsInfo.AddFailed()
-				break SelectLoop
+			t.statisticsInfo.AddLatency(urlAResponse.Latency, urlBResponse.Latency)
+
+			statusDiff := ""
+			if t.Config.CompareStatusCode {
+				statusDiff = compareStatusCode(urlAResponse, urlBResponse)
 			}
+			headerDiff := compareHeaders(t.Config.CompareHeaders, urlAResponse, urlBResponse)
 
-			urlAResponseFieldMap := make(map[string]interface{})
-			urlBResponseFieldMap := make(map[string]interface{})
+			if t.Config.ResponseFormat != constant.ResponseFormatJson {
+				t.compareNonJson(payload, urlAResponse, urlBResponse, statusDiff, headerDiff)
+				break SelectLoop
+			}
 
-			for _, field := range t.Config.IgnoreFields {
-				urlAValue, err := util.SetJsonFieldToNil(urlAResponse, field)
-				if err != nil {
-					logger.Error(t.ctx, "Task_run Failed to set field to nil in urlA response", zap.Any("response", urlAResponse), zap.Any("field", field), zap.Error(err))
-					t.failedCH <- NewFailedOutput(payload, err)
-					t.statisticsInfo.AddFailed()
-					break SelectLoop
-				}
-				urlAResponseFieldMap[field] = urlAValue
-
-				urlBValue, err := util.SetJsonFieldToNil(urlBResponse, field)
-				if err != nil {
-					t.failedCH <- NewFailedOutput(payload, err)
-					t.statisticsInfo.AddFailed()
-					logger.Error(t.ctx, "Task_run Failed to set field to nil in urlB response", zap.Any("response", urlBResponse), zap.Any("field", field), zap.Error(err))
-					break SelectLoop
-				}
-				urlBResponseFieldMap[field] = urlBValue
+			// 状态码不同时直接记录差异，不再判断成功条件和忽略字段
+			if statusDiff != "" {
+				output := t.newDiffOutput(payload, urlAResponse, urlBResponse)
+				output.Diff, output.Differences = util.DiffJson(urlAResponse.Body, urlBResponse.Body, t.cmpOptions...)
+				output.StatusDiff = statusDiff
+				output.HeaderDiff = headerDiff
+				t.statisticsInfo.AddDiff()
+				t.outputCh <- output
+				break SelectLoop
 			}
 
-			diff := cmp.Diff(urlAResponse, urlBResponse)
-			if diff == "" {
-				t.outputCh <- &OutPut{Payload: payload, Diff: diff, UrlAResponse: nil, UrlBResponse: nil}
-				t.statisticsInfo.AddSame()
+			urlABody := urlAResponse.Body
+			urlBBody := urlBResponse.Body
+
+			if !t.responseSuccess(urlABody) || !t.responseSuccess(urlBBody) {
+				logger.Error(t.ctx, "Task_run Response does not meet success conditions", zap.Any("payload", payload), zap.Any("urlAResponse", urlABody), zap.Any("urlBResponse", urlBBody))
+				t.failedCH <- NewFailedOutput(payload, errors.New("response does not meet success conditions"))
+				t.statisticsInfo.AddFailed()
 				break SelectLoop
 			}
 
-			urlAErr := t.recoverFileValue(urlAResponse, urlAResponseFieldMap)
-			urlBErr := t.recoverFileValue(urlBResponse, urlBResponseFieldMap)
-			if urlAErr != nil || urlBErr != nil {
-				logger.Error(t.ctx, "Task_run Failed to set field value in response", zap.Any("payload", payload), zap.Any("urlAErr", urlAErr), zap.Any("urlBErr", urlBErr))
-				t.failedCH <- NewFailedOutput(payload, errors.New("failed to set field value in response: "+cast.ToString(urlAErr)+"; "+cast.ToString(urlBErr)))
+			bodyDiff, err := t.diffJsonBody(urlABody, urlBBody)
+			if err != nil {
+				logger.Error(t.ctx, "Task_run Failed to diff response", zap.Any("payload", payload), zap.Any("urlAResponse", urlABody), zap.Any("urlBResponse", urlBBody), zap.Error(err))
+				t.failedCH <- NewFailedOutput(payload, err)
 				t.statisticsInfo.AddFailed()
 				break SelectLoop
 			}
 
+			if bodyDiff.diff == "" && headerDiff == "" {
+				t.outputCh <- t.newSameOutput(payload, urlAResponse, urlBResponse)
+				t.statisticsInfo.AddSame()
+				break SelectLoop
+			}
+
+			output := t.newDiffOutput(payload, urlAResponse, urlBResponse)
+			output.Diff = bodyDiff.diff
+			output.Differences = bodyDiff.differences
+			output.HeaderDiff = headerDiff
+			output.ArrayDiffs = bodyDiff.arrayDiffs
 			t.statisticsInfo.AddDiff()
-			t.outputCh <- &OutPut{Payload: payload, Diff: diff, UrlAResponse: urlAResponse, UrlBResponse: urlBResponse}
+			t.outputCh <- output
 		}
 	}
 }
 
-func (t *Task) recoverFileValue(jsonData interface{}, valueMap map[string]interface{}) error {
-	for key, value := range valueMap {
-		err := util.SetJsonFieldValue(jsonData, key, value)
-		if err != nil {
-			logger.Error(t.ctx, "Task_recoverFileValue Failed to set field value in jsonData", zap.Any("jsonData", jsonData), zap.Any("field", key), zap.Any("value", value), zap.Error(err))
-			return err
-		}
+// requestUrlA 请求接口A，回放模式下使用录制的响应
+func (t *Task) requestUrlA(payload *Payload) (*Response, error) {
+	if t.Config.Mode == constant.ModeReplay {
+		return t.replayResponse(payload)
+	}
+
+	if err := t.urlALimiter.Wait(t.ctx); err != nil {
+		return nil, err
 	}
-	return nil
+
+	t.statisticsInfo.AddUrlARequest()
+
+	return t.observeRequest(sideA, func() (*Response, error) {
+		return DoRequest(t.ctx, t.UrlAInfo, payload)
+	})
+}
+
+// requestUrlB 请求接口B
+func (t *Task) requestUrlB(payload *Payload) (*Response, error) {
+	if err := t.urlBLimiter.Wait(t.ctx); err != nil {
+		return nil, err
+	}
+
+	t.statisticsInfo.AddUrlBRequest()
+
+	return t.observeRequest(sideB, func() (*Response, error) {
+		return DoRequest(t.ctx, t.UrlBInfo, payload)
+	})
+}
+
+// newSameOutput 创建没有差异的对比结果，只记录请求参数和耗时
+func (t *Task) newSameOutput(payload *Payload, urlAResponse, urlBResponse *Response) *OutPut {
+	output := &OutPut{Payload: payload}
+	t.setLatency(output, urlAResponse, urlBResponse)
+
+	return output
+}
+
+// newDiffOutput 创建有差异的对比结果，开启状态码和响应头对比时记录对应的数据
+func (t *Task) newDiffOutput(payload *Payload, urlAResponse, urlBResponse *Response) *OutPut {
+	output := &OutPut{
+		Payload:      payload,
+		UrlAResponse: urlAResponse.Body,
+		UrlBResponse: urlBResponse.Body,
+		UrlAHeaders:  urlAResponse.Headers,
+		UrlBHeaders:  urlBResponse.Headers,
+	}
+	t.setLatency(output, urlAResponse, urlBResponse)
+
+	if t.Config.CompareStatusCode {
+		output.UrlAStatusCode = urlAResponse.StatusCode
+		output.UrlBStatusCode = urlBResponse.StatusCode
+	}
+
+	return output
+}
+
+// setLatency 记录两个接口的耗时，接口B明显比接口A慢时标记为性能差异
+func (t *Task) setLatency(output *OutPut, urlAResponse, urlBResponse *Response) {
+	output.LatencyA = durationToMilliseconds(urlAResponse.Latency)
+	output.LatencyB = durationToMilliseconds(urlBResponse.Latency)
+
+	// 回放旧的录制文件时没有接口A的耗时，不检查性能差异
+	if t.Config.LatencyRatio > 0 && urlAResponse.Latency > 0 && float64(urlBResponse.Latency) > float64(urlAResponse.Latency)*t.Config.LatencyRatio {
+		output.PerformanceDiff = true
+		t.statisticsInfo.AddPerformanceDiff()
+	}
+}
+
+// durationToMilliseconds 把耗时转换为毫秒，保留三位小数
+func durationToMilliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
 }
 
 // writeOutputToFile 用于将输出结果写入文件
 func (t *Task) writeOutputToFile() error {
 
-	outputFilePath := path.Join(t.Config.WorkDir, t.Config.TaskName+"_output.txt")
+	outputFilePath := t.resultFilePath("_output.txt")
 
-	outputFile, err := os.Create(outputFilePath)
+	outputFile, err := t.openResultFile(outputFilePath)
 	if err != nil {
 		logger.Error(t.ctx, "Task_writeOutputToFile Failed to create output file", zap.String("outputFilePath", outputFilePath), zap.Error(err))
 		return err
@@ -366,49 +821,65 @@ func (t *Task) writeOutputToFile() error {
 		}
 	}()
 
-	defer func() {
-		errInner := outputFile.Sync()
-		if errInner != nil {
-			logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to sync output file", zap.String("outputFilePath", outputFilePath), zap.Error(errInner))
-		}
-	}()
-
 	for {
 		select {
 		case output := <-t.outputCh:
-			if !t.Config.OutputShowNoDiffLine && output != nil && output.Diff == "" {
-				logger.Debug(t.ctx, "Task_writeOutputToFile Skipping output with no diff", zap.Any("task", t), zap.Any("output", output))
-				t.waitGroup.Done()
-				continue
+			t.writeOutput(outputFile, output)
+		case <-t.Done():
+			// 任务结束时把通道中剩余的数据写入文件
+			for {
+				select {
+				case output := <-t.outputCh:
+					t.writeOutput(outputFile, output)
+				default:
+					logger.Debug(t.ctx, "Task_writeOutputToFile Task done, stopping writer", zap.Any("task", t))
+					return nil
+				}
 			}
+		}
+	}
+}
 
-			marshal, err := sonic.Marshal(output)
-			if err != nil {
-				logger.Error(t.ctx, "Task_writeOutputToFile Failed to marshal output", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
-				t.waitGroup.Done()
-				continue
-			}
+// writeOutput 写入一条对比结果
+func (t *Task) writeOutput(outputFile io.Writer, output *OutPut) {
+	t.resultLock.RLock()
+	defer t.resultLock.RUnlock()
 
-			_, err = outputFile.WriteString(string(marshal) + "\n")
-			if err != nil {
-				logger.Error(t.ctx, "Task_writeOutputToFile Failed to write output to file", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
-				t.waitGroup.Done()
-				continue
-			}
+	t.reportCollector.Add(output)
 
-			t.waitGroup.Done()
-		case <-t.ctx.Done():
-			logger.Debug(t.ctx, "Task_writeOutputToFile Context done, stopping writer", zap.Any("task", t))
-			return nil
-		}
+	result := resultSame
+	if output.HasDiff() {
+		result = resultDiff
+	}
+
+	if !t.Config.OutputShowNoDiffLine && result == resultSame && !output.PerformanceDiff {
+		logger.Debug(t.ctx, "Task_writeOutputToFile Skipping output with no diff", zap.Any("task", t), zap.Any("output", output))
+		t.finishPayload(output.Payload, result)
+		return
+	}
+
+	marshal, err := sonic.Marshal(output)
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeOutputToFile Failed to marshal output", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
+		t.finishPayload(output.Payload, result)
+		return
+	}
+
+	_, err = io.WriteString(outputFile, string(marshal)+"\n")
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeOutputToFile Failed to write output to file", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
+		t.finishPayload(output.Payload, result)
+		return
 	}
+
+	t.finishPayload(output.Payload, result)
 }
 
 // writeFailedPayloadToFile 用于将处理失败的 Payload 写入文件
 func (t *Task) writeFailedPayloadToFile() error {
 
-	outputFilePath := path.Join(t.Config.WorkDir, t.Config.TaskName+"_failed_payload.txt")
-	outputFile, err := os.Create(outputFilePath)
+	outputFilePath := t.resultFilePath("_failed_payload.txt")
+	outputFile, err := t.openResultFile(outputFilePath)
 	if err != nil {
 		logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to create output file", zap.String("outputFilePath", outputFilePath), zap.Error(err))
 		return err
@@ -421,45 +892,60 @@ func (t *Task) writeFailedPayloadToFile() error {
 		}
 	}()
 
-	defer func() {
-		errInner := outputFile.Sync()
-		if errInner != nil {
-			logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to sync output file", zap.String("outputFilePath", outputFilePath), zap.Error(errInner))
-		}
-	}()
-
 	for {
 		select {
 		case output := <-t.failedCH:
-			marshal, err := sonic.Marshal(output)
-			if err != nil {
-				logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to marshal output", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
-				t.waitGroup.Done()
-				continue
+			t.writeFailedPayload(outputFile, output)
+		case <-t.Done():
+			// 任务结束时把通道中剩余的数据写入文件
+			for {
+				select {
+				case output := <-t.failedCH:
+					t.writeFailedPayload(outputFile, output)
+				default:
+					logger.Debug(t.ctx, "Task_writeFailedPayloadToFile Task done, stopping writer", zap.Any("task", t))
+					return nil
+				}
 			}
+		}
+	}
+}
 
-			_, err = outputFile.WriteString(string(marshal) + "\n")
-			if err != nil {
-				logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to write output to file", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
-				t.waitGroup.Done()
-				continue
-			}
+// writeFailedPayload 写入一条处理失败的 Payload
+func (t *Task) writeFailedPayload(outputFile io.Writer, output *FailedOutPut) {
+	t.resultLock.RLock()
+	defer t.resultLock.RUnlock()
 
-			t.waitGroup.Done()
-		case <-t.ctx.Done():
-			logger.Debug(t.ctx, "Task_writeFailedPayloadToFile Context done, stopping writer", zap.Any("task", t))
-			return nil
-		}
+	t.recentErrors.add(output.Err)
+
+	marshal, err := sonic.Marshal(output)
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to marshal output", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
+		t.finishPayload(output.payload, resultFailed)
+		return
+	}
+
+	_, err = io.WriteString(outputFile, string(marshal)+"\n")
+	if err != nil {
+		logger.Error(t.ctx, "Task_writeFailedPayloadToFile Failed to write output to file", zap.Any("task", t), zap.Any("output", output), zap.Error(err))
+		t.finishPayload(output.payload, resultFailed)
+		return
 	}
+
+	t.finishPayload(output.payload, resultFailed)
 }
 
 // logStatisticsInfo 用于记录任务的统计信息
 func (t *Task) logStatisticsInfoLoop() {
 	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+
 	for {
 		select {
 		case <-t.ctx.Done():
 			return
+		case <-t.Done():
+			return
 		case <-tick.C:
 			t.logStatisticsInfo()
 		}
@@ -473,8 +959,15 @@ func (t *Task) logStatisticsInfo() {
 		zap.Int64("sameCount:", t.statisticsInfo.GetSameCount()),
 		zap.Int64("diffCount", t.statisticsInfo.GetDiffCount()),
 		zap.Int64("failedCount:", t.statisticsInfo.GetFailedCount()),
+		zap.Int64("recordedCount:", t.statisticsInfo.GetRecordedCount()),
 		zap.String("progress:", t.statisticsInfo.GetProgress()),
 		zap.String("rate:", t.statisticsInfo.GetRate()),
+		zap.String("urlARate:", t.statisticsInfo.GetUrlARate()),
+		zap.String("urlBRate:", t.statisticsInfo.GetUrlBRate()),
+		zap.Int("concurrency:", t.currentConcurrency()),
+		zap.Int64("performanceDiffCount:", t.statisticsInfo.GetPerformanceDiffCount()),
+		zap.Any("urlALatency:", t.statisticsInfo.GetUrlALatency()),
+		zap.Any("urlBLatency:", t.statisticsInfo.GetUrlBLatency()),
 		zap.String("time cost:", t.statisticsInfo.GetTimeCost()),
 		zap.String("time left:", t.statisticsInfo.GetTimeLeft()),
 	)
@@ -499,6 +992,51 @@ func (t *Task) responseSuccess(result interface{}) bool {
 	return true
 }
 
+// Shutdown 停止读取和处理新的请求参数，正在处理的请求参数处理完成并写入文件之后任务结束
+func (t *Task) Shutdown() {
+	t.shutdownChOnce.Do(func() {
+		logger.Info(t.ctx, "Task_Shutdown Shutting down task", zap.String("task", t.Config.TaskName))
+		close(t.shutdownCh)
+
+		// 丢弃通道中还没有开始处理的请求参数
+		go safe.RecoveryWithLogger(t.discardInput, t.ctx, "Task_Shutdown_discardInput")
+	})
+}
+
+func (t *Task) isShutdown() bool {
+	select {
+	case <-t.shutdownCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// discardInput 丢弃输入通道中的请求参数，这些请求参数不会计入断点
+func (t *Task) discardInput() {
+	for {
+		select {
+		case <-t.inputCh:
+			t.waitGroup.Done()
+		case <-t.Done():
+			return
+		}
+	}
+}
+
+// startWriter 启动写文件的子程序，任务结束时会等待子程序把剩余的数据写入文件
+func (t *Task) startWriter(writer func() error, tag string) {
+	t.writerWaitGroup.Add(1)
+
+	go safe.RecoveryWithLoggerAndCallback(func() {
+		defer t.writerWaitGroup.Done()
+
+		if err := writer(); err != nil {
+			t.stop()
+		}
+	}, t.ctx, tag, func() { t.stop() })
+}
+
 func (t *Task) stop() {
 	t.stopChOnce.Do(func() {
 		close(t.stopCh)
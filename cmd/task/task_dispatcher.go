@@ -6,6 +6,7 @@ import (
 
 	"http-diff/lib/concurrency"
 	"http-diff/lib/config"
+	"http-diff/lib/dashboard"
 	"http-diff/lib/logger"
 
 	"go.uber.org/zap"
@@ -27,7 +28,8 @@ type Dispatcher struct {
 	done chan struct{}
 }
 
-func NewDispatcher(ctx context.Context, diffConfigs []config.DiffConfig) (*Dispatcher, error) {
+// NewDispatcher 创建调度器，resume 为 true 时所有任务从上次保存的断点继续运行
+func NewDispatcher(ctx context.Context, diffConfigs []config.DiffConfig, resume bool) (*Dispatcher, error) {
 
 	dispatcher := &Dispatcher{
 		ctx:             ctx,
@@ -39,6 +41,7 @@ func NewDispatcher(ctx context.Context, diffConfigs []config.DiffConfig) (*Dispa
 	for _, diffConfig := range diffConfigs {
 
 		taskConfig := initTaskConfig(diffConfig)
+		taskConfig.Resume = resume
 
 		// 初始化任务
 		task, err := InitTask(ctx, taskConfig)
@@ -70,25 +73,87 @@ func (d *Dispatcher) Start() {
 	close(d.done)
 }
 
+// Shutdown 通知所有任务停止处理新的请求参数，任务结束之后 Done 返回的通道会被关闭
+func (d *Dispatcher) Shutdown() {
+	logger.Info(d.ctx, "Dispatcher shutting down", zap.Int("taskCount", len(d.tasks)))
+
+	for _, task := range d.tasks {
+		task.Shutdown()
+	}
+}
+
 func (d *Dispatcher) Done() <-chan struct{} {
 	return d.done
 }
 
+// Progress 所有任务当前的进度
+func (d *Dispatcher) Progress() []dashboard.TaskProgress {
+	progresses := make([]dashboard.TaskProgress, 0, len(d.tasks))
+	for _, task := range d.tasks {
+		progresses = append(progresses, task.Progress())
+	}
+
+	return progresses
+}
+
 // initTaskConfig 初始化任务配置
 func initTaskConfig(diffConfig config.DiffConfig) Config {
 	return Config{
-		TaskName:             diffConfig.Name,
-		WorkDir:              diffConfig.WorkDir,
-		Payload:              diffConfig.Payload,
-		WaitTime:             diffConfig.WaitTime,
-		Concurrency:          diffConfig.Concurrency,
-		UrlA:                 diffConfig.UrlA,
-		UrlB:                 diffConfig.UrlB,
-		Method:               diffConfig.Method,
-		ContentType:          diffConfig.ContentType,
-		IgnoreFields:         strings.Split(diffConfig.IgnoreFields, ","),
-		OutputShowNoDiffLine: diffConfig.OutputShowNoDiffLine,
-		LogStatistics:        diffConfig.LogStatistics,
-		SuccessConditions:    diffConfig.SuccessConditions,
+		TaskName:               diffConfig.Name,
+		WorkDir:                diffConfig.WorkDir,
+		Payload:                diffConfig.Payload,
+		ColumnMapping:          splitFields(diffConfig.ColumnMapping),
+		PayloadTemplate:        diffConfig.PayloadTemplate,
+		Variables:              splitFields(diffConfig.Variables),
+		CompressOutput:         diffConfig.CompressOutput,
+		WaitTime:               diffConfig.WaitTime,
+		InputBufferSize:        diffConfig.InputBufferSize,
+		OutputBufferSize:       diffConfig.OutputBufferSize,
+		QPS:                    diffConfig.QPS,
+		Burst:                  diffConfig.Burst,
+		UrlAQPS:                diffConfig.UrlAQPS,
+		UrlABurst:              diffConfig.UrlABurst,
+		UrlBQPS:                diffConfig.UrlBQPS,
+		UrlBBurst:              diffConfig.UrlBBurst,
+		Concurrency:            diffConfig.Concurrency,
+		AdaptiveConcurrency:    diffConfig.AdaptiveConcurrency,
+		MinConcurrency:         diffConfig.MinConcurrency,
+		AdaptiveMaxErrorRate:   diffConfig.AdaptiveMaxErrorRate,
+		AdaptiveMaxLatency:     diffConfig.AdaptiveMaxLatency,
+		AdaptiveInterval:       diffConfig.AdaptiveInterval,
+		Mode:                   diffConfig.Mode,
+		RecordFile:             diffConfig.RecordFile,
+		UrlA:                   diffConfig.UrlA,
+		UrlB:                   diffConfig.UrlB,
+		UrlARewrites:           diffConfig.UrlARewrites,
+		UrlBRewrites:           diffConfig.UrlBRewrites,
+		Method:                 diffConfig.Method,
+		ContentType:            diffConfig.ContentType,
+		ResponseFormat:         diffConfig.ResponseFormat,
+		CompareStatusCode:      diffConfig.CompareStatusCode,
+		CompareHeaders:         splitFields(diffConfig.CompareHeaders),
+		IgnoreFields:           splitFields(diffConfig.IgnoreFields),
+		UnorderedArrays:        splitFields(diffConfig.UnorderedArrays),
+		FloatTolerance:         diffConfig.FloatTolerance,
+		FloatRelativeTolerance: diffConfig.FloatRelativeTolerance,
+		FloatPathTolerances:    splitFields(diffConfig.FloatPathTolerances),
+		StringNumberEqual:      diffConfig.StringNumberEqual,
+		LatencyRatio:           diffConfig.LatencyRatio,
+		OutputShowNoDiffLine:   diffConfig.OutputShowNoDiffLine,
+		LogStatistics:          diffConfig.LogStatistics,
+		SuccessConditions:      diffConfig.SuccessConditions,
 	}
 }
+
+// splitFields 按英文逗号分割配置项，去掉空白和空字段
+func splitFields(value string) []string {
+	result := make([]string, 0)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			result = append(result, field)
+		}
+	}
+
+	return result
+}
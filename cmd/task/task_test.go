@@ -0,0 +1,178 @@
+package task
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"http-diff/constant"
+	"http-diff/lib/config"
+	"http-diff/lib/http"
+	"http-diff/lib/logger"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	logDir, err := os.MkdirTemp("", "http-diff-task")
+	if err != nil {
+		panic(err)
+	}
+
+	logger.Init("TestTask", config.LoggerConfig{Path: logDir, Level: "ERROR"})
+	http.Init(config.FastHttp{ReadTimeOut: time.Second, WriteTimeOut: time.Second, MaxConnsPerHost: 16})
+
+	code := m.Run()
+	_ = os.RemoveAll(logDir)
+	os.Exit(code)
+}
+
+// newTestConfig 测试用的任务配置，请求参数文件位于工作目录中
+func newTestConfig(name string, workDir string, payload string) Config {
+	return Config{
+		TaskName:         name,
+		WorkDir:          workDir,
+		Payload:          payload,
+		InputBufferSize:  10,
+		OutputBufferSize: 10,
+		Concurrency:      2,
+		Mode:             constant.ModeDiff,
+		RecordFile:       name + "_record.txt",
+		Method:           constant.GET,
+		ResponseFormat:   constant.ResponseFormatJson,
+	}
+}
+
+// writeTestFile 在工作目录中写入文件，每个元素一行
+func writeTestFile(t *testing.T, workDir string, name string, lines ...string) {
+	err := os.WriteFile(path.Join(workDir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	assert.Nil(t, err)
+}
+
+// readTestFile 读取工作目录中的文件，按行返回，文件不存在时返回 nil
+func readTestFile(t *testing.T, workDir string, name string) []string {
+	content, err := os.ReadFile(path.Join(workDir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	assert.Nil(t, err)
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+// runTestTask 初始化并运行任务，超时未结束时测试失败
+func runTestTask(t *testing.T, cfg Config) *Task {
+	task, err := InitTask(context.Background(), cfg)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		task.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not finish in time")
+	}
+
+	return task
+}
+
+func TestRunShutdown(t *testing.T) {
+	started := make(chan struct{}, 100)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		started <- struct{}{}
+		time.Sleep(20 * time.Millisecond)
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"id":"` + request.URL.Query().Get("id") + `"}`))
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	lines := make([]string, 0, 100)
+	for i := 1; i <= 100; i++ {
+		lines = append(lines, `{"params":{"id":"`+strconv.Itoa(i)+`"}}`)
+	}
+	writeTestFile(t, workDir, "payload.txt", lines...)
+
+	cfg := newTestConfig("shutdown", workDir, "payload.txt")
+	cfg.UrlA = server.URL + "/a"
+	cfg.UrlB = server.URL + "/b"
+	cfg.OutputShowNoDiffLine = true
+
+	task, err := InitTask(context.Background(), cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		task.Run()
+		close(done)
+	}()
+
+	// 开始请求之后停止读取，读文件的协程提前结束，已经放入通道的请求参数被丢弃
+	<-started
+	task.Shutdown()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task did not finish after shutdown")
+	}
+
+	processed := task.statisticsInfo.GetProcessedCount()
+	assert.Greater(t, processed, int64(0))
+	assert.Less(t, processed, int64(100))
+	assert.Equal(t, int64(0), task.statisticsInfo.GetFailedCount())
+
+	// 正在处理的请求参数处理完成并写入文件之后才结束，断点中的进度和结果文件一致
+	output := readTestFile(t, workDir, "shutdown_output.txt")
+	assert.Len(t, output, int(processed))
+
+	content, err := os.ReadFile(path.Join(workDir, "shutdown_checkpoint.json"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	checkpoint := &Checkpoint{}
+	assert.Nil(t, sonic.Unmarshal(content, checkpoint))
+	assert.Equal(t, processed, int64(checkpoint.Files[0].Line+len(checkpoint.Files[0].Done)))
+	assert.Equal(t, processed, checkpoint.SameCount)
+	assert.Equal(t, int(processed), checkpoint.ResultLines["shutdown_output.txt"])
+}
+
+func TestRunStopsWhenReaderFails(t *testing.T) {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(writer nethttp.ResponseWriter, request *nethttp.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"id":"` + request.URL.Query().Get("id") + `"}`))
+	}))
+	defer server.Close()
+
+	workDir := t.TempDir()
+	writeTestFile(t, workDir, "a.txt", `{"params":{"id":"1"}}`, `{"params":{"id":"2"}}`)
+	// 不是 gzip 格式的文件无法打开，读文件的协程出错之后停止任务
+	writeTestFile(t, workDir, "b.txt.gz", `{"params":{"id":"3"}}`)
+
+	cfg := newTestConfig("reader", workDir, "a.txt,b.txt.gz")
+	cfg.UrlA = server.URL + "/a"
+	cfg.UrlB = server.URL + "/b"
+
+	task := runTestTask(t, cfg)
+
+	recent := task.recentErrors.list()
+	if assert.NotEmpty(t, recent) {
+		assert.Contains(t, recent[len(recent)-1], "b.txt.gz")
+	}
+	assert.Equal(t, 0, task.checkpoint.processedLine(1))
+}
@@ -0,0 +1,151 @@
+package task
+
+import (
+	"errors"
+	"strings"
+
+	"http-diff/constant"
+	"http-diff/lib/logger"
+	"http-diff/lib/variable"
+
+	"github.com/bytedance/sonic"
+	"go.uber.org/zap"
+)
+
+// templateCheckpointFile 使用请求参数模板时断点中记录的文件名，行号为生成请求参数的序号
+const templateCheckpointFile = "payload_template"
+
+// initPayloadTemplate 解析模板变量和请求参数模板，没有配置变量和模板时都返回 nil
+//
+// 配置了请求参数模板时根据迭代变量生成请求参数，否则 payload 文件中包含变量的行会在请求之前替换变量
+func initPayloadTemplate(cfg Config) (*variable.Set, *variable.Template, error) {
+	if len(cfg.Variables) == 0 && cfg.PayloadTemplate == "" {
+		return nil, nil, nil
+	}
+
+	variables, err := variable.Parse(cfg.Variables, cfg.WorkDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 回放时按照请求参数查找录制的响应，每次生成的值不同时无法匹配
+	if (cfg.Mode == constant.ModeRecord || cfg.Mode == constant.ModeReplay) && variables.HasGenerator() {
+		return nil, nil, errors.New("random, uuid and now variables cannot be used in record and replay mode")
+	}
+
+	if cfg.PayloadTemplate == "" {
+		if variables.HasIterator() {
+			return nil, nil, errors.New("range and csv variables can only be used in payload_template")
+		}
+
+		return variables, nil, nil
+	}
+
+	if !variables.HasIterator() {
+		return nil, nil, errors.New("payload_template requires at least one range or csv variable")
+	}
+
+	payloadTemplate, err := variable.ParseTemplate(cfg.PayloadTemplate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := variables.Check(payloadTemplate); err != nil {
+		return nil, nil, err
+	}
+
+	// 使用第一组变量检查模板生成的是否是合法的请求参数
+	payload := &Payload{template: payloadTemplate, values: variables.Iterate(0)}
+	if err := renderPayload(variables, payload); err != nil {
+		return nil, nil, err
+	}
+
+	return variables, payloadTemplate, nil
+}
+
+// renderPayload 替换请求参数模板中的变量，随机数、uuid 和当前时间在替换时生成
+func renderPayload(variables *variable.Set, payload *Payload) error {
+	if payload.template == nil {
+		return nil
+	}
+
+	values := variables.Generate()
+	for name, value := range payload.values {
+		values[name] = value
+	}
+
+	// 模板是 JSON 格式，变量的值按照 JSON 字符串转义
+	for name, value := range values {
+		values[name] = escapeTemplateValue(value)
+	}
+
+	line, err := payload.template.Execute(values)
+	if err != nil {
+		return errors.New("failed to render payload template: " + err.Error())
+	}
+
+	if err := sonic.Unmarshal([]byte(line), payload); err != nil {
+		return errors.New("invalid payload rendered from template: " + err.Error() + ", payload: " + line)
+	}
+
+	if err := checkPayload(payload); err != nil {
+		return errors.New("invalid payload rendered from template: " + err.Error())
+	}
+
+	payload.template = nil
+	payload.values = nil
+
+	return nil
+}
+
+// escapeTemplateValue 按照 JSON 字符串转义变量的值，不包含两侧的引号
+func escapeTemplateValue(value string) string {
+	marshal, err := sonic.MarshalString(value)
+	if err != nil {
+		return value
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(marshal, `"`), `"`)
+}
+
+// parseLineTemplate 解析 payload 文件中包含变量的行
+func (t *Task) parseLineTemplate(line string) (*variable.Template, error) {
+	lineTemplate, err := variable.ParseTemplate(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.variables.Check(lineTemplate); err != nil {
+		return nil, err
+	}
+
+	return lineTemplate, nil
+}
+
+// runGenerator 根据迭代变量的所有取值组合生成请求参数并放入通道，断点续跑时跳过已经处理完成的请求参数
+func (t *Task) runGenerator() {
+	defer t.waitGroup.Done()
+
+	total := t.variables.Count()
+	processed := int64(t.checkpoint.processedLine(0))
+
+	logger.Info(t.ctx, "Task_runGenerator Starting to generate payloads", zap.String("task", t.Config.TaskName), zap.Int64("total", total), zap.Int64("processed", processed))
+
+	for index := processed; index < total; index++ {
+		if t.checkpoint.processed(0, int(index)+1) {
+			continue
+		}
+
+		payload := &Payload{
+			lineNumber: int(index) + 1,
+			template:   t.payloadTemplate,
+			values:     t.variables.Iterate(index),
+		}
+
+		if !t.enqueuePayload(payload) {
+			return
+		}
+	}
+
+	logger.Info(t.ctx, "Task_runGenerator Finished generating payloads", zap.String("task", t.Config.TaskName), zap.Int64("total", total))
+}
@@ -0,0 +1,10 @@
+package constant
+
+const (
+	// ModeDiff 对比模式，同时请求 url_a 和 url_b 并对比响应
+	ModeDiff = "diff"
+	// ModeRecord 录制模式，只请求 url_a 并把响应记录到文件中
+	ModeRecord = "record"
+	// ModeReplay 回放模式，使用录制的响应作为 url_a 的响应，和 url_b 的响应对比
+	ModeReplay = "replay"
+)
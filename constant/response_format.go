@@ -0,0 +1,8 @@
+package constant
+
+const (
+	ResponseFormatJson = "json"
+	ResponseFormatText = "text"
+	ResponseFormatXml  = "xml"
+	ResponseFormatRaw  = "raw"
+)
@@ -0,0 +1,138 @@
+package concurrency
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig 自适应并发配置
+type AdaptiveConfig struct {
+	// Min 最小并发数，也是初始并发数
+	Min int
+	// Max 最大并发数
+	Max int
+	// MaxErrorRate 错误率阈值，超过之后减少并发数
+	MaxErrorRate float64
+	// MaxLatency p95 耗时阈值，超过之后减少并发数
+	MaxLatency time.Duration
+}
+
+// AdaptiveStatistics 一个统计周期内的请求情况
+type AdaptiveStatistics struct {
+	Count      int64
+	ErrorCount int64
+	ErrorRate  float64
+	P95Latency time.Duration
+}
+
+// AdaptiveLimiter 使用 AIMD（加法增加，乘法减少）算法调整并发数
+//
+// 每个统计周期内错误率和 p95 耗时都低于阈值时并发数加 1，任意一个超过阈值时并发数减半。nil 表示不限制并发数
+type AdaptiveLimiter struct {
+	lock   sync.Mutex
+	config AdaptiveConfig
+
+	// limit 当前并发数
+	limit int
+	// changed 并发数变化时关闭，用于唤醒等待的协程
+	changed chan struct{}
+
+	// latencies 当前统计周期内的请求耗时
+	latencies  []time.Duration
+	errorCount int64
+}
+
+// NewAdaptiveLimiter 创建自适应并发限制器，最小并发数小于 1 时按 1 处理，最大并发数小于最小并发数时按最小并发数处理
+func NewAdaptiveLimiter(config AdaptiveConfig) *AdaptiveLimiter {
+	if config.Min < 1 {
+		config.Min = 1
+	}
+
+	if config.Max < config.Min {
+		config.Max = config.Min
+	}
+
+	return &AdaptiveLimiter{
+		config:  config,
+		limit:   config.Min,
+		changed: make(chan struct{}),
+	}
+}
+
+// Limit 当前并发数
+func (a *AdaptiveLimiter) Limit() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.limit
+}
+
+// Active 编号为 index 的协程是否可以运行，不能运行时返回的通道会在并发数变化时关闭
+func (a *AdaptiveLimiter) Active(index int) (bool, <-chan struct{}) {
+	if a == nil {
+		return true, nil
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return index < a.limit, a.changed
+}
+
+// Observe 记录一次请求的耗时和结果
+func (a *AdaptiveLimiter) Observe(latency time.Duration, failed bool) {
+	if a == nil {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.latencies = append(a.latencies, latency)
+	if failed {
+		a.errorCount++
+	}
+}
+
+// Adjust 根据上一个统计周期的请求情况调整并发数，并开始新的统计周期。统计周期内没有请求时不调整
+func (a *AdaptiveLimiter) Adjust() (int, AdaptiveStatistics) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	statistics := AdaptiveStatistics{
+		Count:      int64(len(a.latencies)),
+		ErrorCount: a.errorCount,
+	}
+
+	if statistics.Count == 0 {
+		return a.limit, statistics
+	}
+
+	sort.Slice(a.latencies, func(i, j int) bool {
+		return a.latencies[i] < a.latencies[j]
+	})
+	statistics.ErrorRate = float64(statistics.ErrorCount) / float64(statistics.Count)
+	statistics.P95Latency = a.latencies[(len(a.latencies)*95+99)/100-1]
+
+	a.latencies = a.latencies[:0]
+	a.errorCount = 0
+
+	limit := a.limit
+	if statistics.ErrorRate > a.config.MaxErrorRate || (a.config.MaxLatency > 0 && statistics.P95Latency > a.config.MaxLatency) {
+		limit = limit / 2
+		if limit < a.config.Min {
+			limit = a.config.Min
+		}
+	} else if limit < a.config.Max {
+		limit++
+	}
+
+	if limit != a.limit {
+		a.limit = limit
+		close(a.changed)
+		a.changed = make(chan struct{})
+	}
+
+	return a.limit, statistics
+}
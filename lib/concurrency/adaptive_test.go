@@ -0,0 +1,71 @@
+package concurrency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAdaptiveLimiter(t *testing.T) {
+	limiter := NewAdaptiveLimiter(AdaptiveConfig{Min: 0, Max: 0})
+	assert.Equal(t, 1, limiter.Limit())
+
+	var nilLimiter *AdaptiveLimiter
+	active, _ := nilLimiter.Active(100)
+	assert.True(t, active)
+}
+
+func TestAdaptiveLimiterAdjust(t *testing.T) {
+	limiter := NewAdaptiveLimiter(AdaptiveConfig{Min: 2, Max: 4, MaxErrorRate: 0.1, MaxLatency: 100 * time.Millisecond})
+
+	active, changed := limiter.Active(2)
+	assert.False(t, active)
+
+	// 没有请求时不调整
+	limit, _ := limiter.Adjust()
+	assert.Equal(t, 2, limit)
+
+	// 错误率和耗时都低于阈值时加 1
+	for i := 0; i < 100; i++ {
+		limiter.Observe(10*time.Millisecond, false)
+	}
+	limit, statistics := limiter.Adjust()
+	assert.Equal(t, 3, limit)
+	assert.Equal(t, int64(100), statistics.Count)
+	assert.Equal(t, 10*time.Millisecond, statistics.P95Latency)
+
+	// 并发数变化时唤醒等待的协程
+	select {
+	case <-changed:
+	default:
+		assert.Fail(t, "changed channel should be closed")
+	}
+	active, _ = limiter.Active(2)
+	assert.True(t, active)
+
+	// 不超过最大并发数
+	limiter.Observe(10*time.Millisecond, false)
+	limit, _ = limiter.Adjust()
+	assert.Equal(t, 4, limit)
+	limiter.Observe(10*time.Millisecond, false)
+	limit, _ = limiter.Adjust()
+	assert.Equal(t, 4, limit)
+
+	// p95 耗时超过阈值时减半
+	for i := 0; i < 90; i++ {
+		limiter.Observe(10*time.Millisecond, false)
+	}
+	for i := 0; i < 10; i++ {
+		limiter.Observe(time.Second, false)
+	}
+	limit, statistics = limiter.Adjust()
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, time.Second, statistics.P95Latency)
+
+	// 错误率超过阈值时减半，不小于最小并发数
+	limiter.Observe(10*time.Millisecond, true)
+	limit, statistics = limiter.Adjust()
+	assert.Equal(t, 2, limit)
+	assert.Equal(t, float64(1), statistics.ErrorRate)
+}
@@ -9,11 +9,14 @@ type Configs struct {
 	App          App          `mapstructure:"app"`
 	LoggerConfig LoggerConfig `mapstructure:"log"`
 	FastHttp     FastHttp     `mapstructure:"fast_http"`
+	Metrics      Metrics      `mapstructure:"metrics"`
 	DiffConfigs  []DiffConfig `mapstructure:"diff_configs"`
 }
 
 type App struct {
 	Name string `mapstructure:"name"`
+	// ShutdownGracePeriod 收到终止信号之后等待正在处理的请求完成的最长时间，超过之后强制结束任务
+	ShutdownGracePeriod time.Duration `mapstructure:"shutdown_grace_period"`
 }
 
 // LoggerConfig 日志配置
@@ -46,18 +49,56 @@ type FastHttp struct {
 	RetryTimes          int           `mapstructure:"retry_times"`
 }
 
+// Metrics Prometheus 指标配置
+type Metrics struct {
+	// Address 指标服务监听的地址，例如 :9090，为空时不启动指标服务
+	Address string `mapstructure:"address"`
+	// Path 指标的访问路径，默认 /metrics
+	Path string `mapstructure:"path"`
+}
+
 type DiffConfig struct {
-	Name                 string        `mapstructure:"name"`
-	Concurrency          int           `mapstructure:"concurrency"` // 并发数
-	WaitTime             time.Duration `mapstructure:"wait_time"`   // 等待时间，每个请求完成之后等待的时间，可以用来限制请求的频率
-	WorkDir              string        `mapstructure:"work_dir"`    // 工作目录
-	Payload              string        `mapstructure:"payload"`     // 请求体内容,多个文件用逗号分割
-	UrlA                 string        `mapstructure:"url_a"`
-	UrlB                 string        `mapstructure:"url_b"`
-	Method               string        `mapstructure:"method"`
-	ContentType          string        `mapstructure:"content_type"`
-	IgnoreFields         string        `mapstructure:"ignore_fields"`            // 忽略的字段，多个字段用逗号分割
-	OutputShowNoDiffLine bool          `mapstructure:"output_show_no_diff_line"` // 输出是否展示没有差异的行，true 展示，false 不展示
-	LogStatistics        bool          `mapstructure:"log_statistics"`           // 是否记录统计日志
-	SuccessConditions    string        `mapstructure:"success_conditions"`       // 成功条件，多个条件用逗号分割
+	Name                   string        `mapstructure:"name"`
+	Concurrency            int           `mapstructure:"concurrency"`             // 并发数
+	WaitTime               time.Duration `mapstructure:"wait_time"`               // 等待时间，每个请求完成之后等待的时间，可以用来限制请求的频率
+	InputBufferSize        int           `mapstructure:"input_buffer_size"`       // 请求参数通道的缓冲区大小，默认 100000
+	OutputBufferSize       int           `mapstructure:"output_buffer_size"`      // 对比结果、录制数据和错误数据通道的缓冲区大小，默认 100000
+	QPS                    float64       `mapstructure:"qps"`                     // 任务每秒最多处理的请求数量，所有并发共享，小于等于 0 表示不限制
+	Burst                  int           `mapstructure:"burst"`                   // 任务允许的最大突发请求数量，默认 1
+	UrlAQPS                float64       `mapstructure:"url_a_qps"`               // 每秒最多请求 url_a 的次数，小于等于 0 表示不限制
+	UrlABurst              int           `mapstructure:"url_a_burst"`             // 请求 url_a 允许的最大突发请求数量，默认 1
+	UrlBQPS                float64       `mapstructure:"url_b_qps"`               // 每秒最多请求 url_b 的次数，小于等于 0 表示不限制
+	UrlBBurst              int           `mapstructure:"url_b_burst"`             // 请求 url_b 允许的最大突发请求数量，默认 1
+	AdaptiveConcurrency    bool          `mapstructure:"adaptive_concurrency"`    // 是否根据错误率和耗时自动调整并发数，开启后 concurrency 为最大并发数
+	MinConcurrency         int           `mapstructure:"min_concurrency"`         // 自适应并发的最小并发数，也是初始并发数，默认 1
+	AdaptiveMaxErrorRate   float64       `mapstructure:"adaptive_max_error_rate"` // 自适应并发的错误率阈值，默认 0.05
+	AdaptiveMaxLatency     time.Duration `mapstructure:"adaptive_max_latency"`    // 自适应并发的 p95 耗时阈值，默认 1s
+	AdaptiveInterval       time.Duration `mapstructure:"adaptive_interval"`       // 自适应并发的调整周期，默认 5s
+	WorkDir                string        `mapstructure:"work_dir"`                // 工作目录
+	Payload                string        `mapstructure:"payload"`                 // 请求体内容,多个文件用逗号分割，支持 glob 模式，.gz 和 .zst 文件自动解压
+	ColumnMapping          string        `mapstructure:"column_mapping"`          // CSV 和 TSV 格式的 payload 文件的列映射，格式为 列名:目标[:类型]，多个用逗号分割，默认所有列作为 URL 参数
+	PayloadTemplate        string        `mapstructure:"payload_template"`        // 请求参数模板，格式和 payload 文件的一行一致，使用 {{变量名}} 引用变量，设置后不再读取 payload 文件
+	Variables              string        `mapstructure:"variables"`               // 模板变量，格式为 名称:类型[:参数]，多个用逗号分割，类型为 range、csv、random、uuid、now
+	CompressOutput         string        `mapstructure:"compress_output"`         // 对比结果和错误数据文件的压缩格式 gzip、zstd，默认不压缩
+	Mode                   string        `mapstructure:"mode"`                    // 运行模式 diff、record、replay，默认 diff
+	RecordFile             string        `mapstructure:"record_file"`             // 录制文件，默认为 {任务名}_record.txt
+	UrlA                   string        `mapstructure:"url_a"`
+	UrlB                   string        `mapstructure:"url_b"`
+	UrlARewrites           []string      `mapstructure:"url_a_rewrites"` // 请求 url_a 之前对请求路径、请求头、URL 参数和请求体的改写规则，格式为 对象.动作:参数[:参数]
+	UrlBRewrites           []string      `mapstructure:"url_b_rewrites"` // 请求 url_b 之前的改写规则，格式和 url_a_rewrites 一致
+	Method                 string        `mapstructure:"method"`
+	ContentType            string        `mapstructure:"content_type"`
+	ResponseFormat         string        `mapstructure:"response_format"`          // 响应数据格式 json、text、xml、raw，默认 json
+	CompareStatusCode      bool          `mapstructure:"compare_status_code"`      // 是否对比状态码，开启后状态码不是 200 的请求也会参与对比
+	CompareHeaders         string        `mapstructure:"compare_headers"`          // 需要对比的响应头，多个用逗号分割
+	IgnoreFields           string        `mapstructure:"ignore_fields"`            // 忽略的字段，多个字段用逗号分割
+	UnorderedArrays        string        `mapstructure:"unordered_arrays"`         // 无序数组，格式为 路径:key，多个用逗号分割，* 表示所有数组
+	FloatTolerance         float64       `mapstructure:"float_tolerance"`          // 数字的绝对容差
+	FloatRelativeTolerance float64       `mapstructure:"float_relative_tolerance"` // 数字的相对容差
+	FloatPathTolerances    string        `mapstructure:"float_path_tolerances"`    // 指定路径的数字容差，格式为 路径:绝对容差:相对容差，多个用逗号分割
+	StringNumberEqual      bool          `mapstructure:"string_number_equal"`      // 字符串和数字表示相同的值时是否认为相等
+	LatencyRatio           float64       `mapstructure:"latency_ratio"`            // urlB 的耗时超过 urlA 的耗时乘以该比例时记为性能差异，小于等于 0 时不检查
+	OutputShowNoDiffLine   bool          `mapstructure:"output_show_no_diff_line"` // 输出是否展示没有差异的行，true 展示，false 不展示
+	LogStatistics          bool          `mapstructure:"log_statistics"`           // 是否记录统计日志
+	SuccessConditions      string        `mapstructure:"success_conditions"`       // 成功条件，多个条件用逗号分割
 }
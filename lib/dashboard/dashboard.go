@@ -0,0 +1,161 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// barWidth 进度条的宽度
+	barWidth = 30
+	// maxErrorLength 每条错误信息最多展示的字符数
+	maxErrorLength = 100
+)
+
+// TaskProgress 任务的进度信息
+type TaskProgress struct {
+	Name string
+	// Total 请求参数总行数，小于等于 0 表示还不知道总数
+	Total     int64
+	Processed int64
+	Same      int64
+	Diff      int64
+	Failed    int64
+	// Recorded 录制模式下录制成功的数量
+	Recorded int64
+	// TimeLeft 预计剩余时间
+	TimeLeft string
+	// RecentErrors 最近的错误信息，按时间从旧到新排列
+	RecentErrors []string
+	// Finished 任务是否已经结束
+	Finished bool
+}
+
+// Dashboard 在终端中原地刷新展示任务进度
+type Dashboard struct {
+	writer io.Writer
+
+	// lastLines 上次输出的行数，刷新时光标先上移这么多行
+	lastLines int
+	// lastTime 上次刷新的时间，用于计算处理速率
+	lastTime time.Time
+	// lastProcessed 上次刷新时每个任务已处理的数量
+	lastProcessed map[string]int64
+}
+
+func New(writer io.Writer) *Dashboard {
+	return &Dashboard{
+		writer:        writer,
+		lastProcessed: make(map[string]int64),
+	}
+}
+
+// IsTerminal 判断文件是否是终端
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Render 清除上次输出的内容并输出最新的进度
+func (d *Dashboard) Render(tasks []TaskProgress) {
+	lines := d.render(tasks, time.Now())
+
+	builder := &strings.Builder{}
+	if d.lastLines > 0 {
+		// 光标上移到上次输出的第一行，清除到屏幕末尾
+		builder.WriteString(fmt.Sprintf("\033[%dA", d.lastLines))
+	}
+	builder.WriteString("\033[J")
+	for _, line := range lines {
+		builder.WriteString(line + "\n")
+	}
+
+	_, _ = io.WriteString(d.writer, builder.String())
+	d.lastLines = len(lines)
+}
+
+// render 生成每个任务的进度行，任务有错误时在下面展示最近的错误
+func (d *Dashboard) render(tasks []TaskProgress, now time.Time) []string {
+	elapsed := now.Sub(d.lastTime).Seconds()
+
+	nameWidth := 0
+	for _, task := range tasks {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(task.Name))
+	}
+
+	lines := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		rate := 0.0
+		if lastProcessed, ok := d.lastProcessed[task.Name]; ok && elapsed > 0 {
+			rate = float64(task.Processed-lastProcessed) / elapsed
+		}
+		d.lastProcessed[task.Name] = task.Processed
+
+		lines = append(lines, formatTask(task, nameWidth, rate))
+		for _, err := range task.RecentErrors {
+			lines = append(lines, strings.Repeat(" ", nameWidth)+"  error: "+truncate(err, maxErrorLength))
+		}
+	}
+	d.lastTime = now
+
+	return lines
+}
+
+// formatTask 格式化任务的进度行
+func formatTask(task TaskProgress, nameWidth int, rate float64) string {
+	name := task.Name + strings.Repeat(" ", nameWidth-utf8.RuneCountInString(task.Name))
+
+	bar, percent, total, timeLeft := "", "", "", task.TimeLeft
+	if task.Total > 0 {
+		ratio := float64(task.Processed) / float64(task.Total)
+		bar = progressBar(ratio, barWidth)
+		percent = strconv.FormatFloat(ratio*100, 'f', 2, 64) + "%"
+		total = strconv.FormatInt(task.Total, 10)
+	} else {
+		bar = "[" + strings.Repeat("?", barWidth) + "]"
+		percent = "-"
+		total = "unknown"
+		timeLeft = "-"
+	}
+
+	state := "ETA:" + timeLeft
+	if task.Finished {
+		state = "done"
+	}
+
+	// 录制模式下没有对比结果，只展示录制成功的数量
+	counts := fmt.Sprintf("same:%d diff:%d failed:%d", task.Same, task.Diff, task.Failed)
+	if task.Recorded > 0 {
+		counts = fmt.Sprintf("recorded:%d failed:%d", task.Recorded, task.Failed)
+	}
+
+	return fmt.Sprintf("%s %s %7s %d/%s %s rate:%.0f req/s %s",
+		name, bar, percent, task.Processed, total, counts, rate, state)
+}
+
+// progressBar 生成进度条，ratio 的取值范围为 0 到 1
+func progressBar(ratio float64, width int) string {
+	ratio = min(max(ratio, 0), 1)
+	filled := int(ratio * float64(width))
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// truncate 超过最大长度时截断并在末尾加上省略号，换行替换为空格避免破坏刷新
+func truncate(value string, maxLength int) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	if utf8.RuneCountInString(value) <= maxLength {
+		return value
+	}
+
+	return string([]rune(value)[:maxLength]) + "..."
+}
@@ -0,0 +1,59 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBar(t *testing.T) {
+	assert.Equal(t, "[----]", progressBar(0, 4))
+	assert.Equal(t, "[##--]", progressBar(0.5, 4))
+	assert.Equal(t, "[####]", progressBar(1, 4))
+	assert.Equal(t, "[####]", progressBar(1.5, 4))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 3))
+	assert.Equal(t, "ab...", truncate("abc", 2))
+	assert.Equal(t, "a b", truncate("a\nb", 3))
+	assert.Equal(t, "中文...", truncate("中文测试", 2))
+}
+
+func TestRender(t *testing.T) {
+	d := New(&bytes.Buffer{})
+	now := time.Now()
+
+	tasks := []TaskProgress{
+		{Name: "task_1", Total: 100, Processed: 50, Same: 40, Diff: 5, Failed: 5, TimeLeft: "10s", RecentErrors: []string{"timeout"}},
+		{Name: "t2", Processed: 10, Same: 10},
+		{Name: "t3", Processed: 8, Recorded: 7, Failed: 1},
+	}
+	lines := d.render(tasks, now)
+	assert.Equal(t, 4, len(lines))
+	assert.Contains(t, lines[0], "task_1 [###############---------------]  50.00% 50/100 same:40 diff:5 failed:5 rate:0 req/s ETA:10s")
+	assert.Equal(t, "        error: timeout", lines[1])
+	assert.Contains(t, lines[2], "t2     ["+strings.Repeat("?", barWidth)+"]       - 10/unknown")
+	assert.Contains(t, lines[3], "8/unknown recorded:7 failed:1 rate:")
+
+	// 第二次刷新时按两次刷新之间处理的数量计算速率
+	tasks[0].Processed = 70
+	tasks[0].Finished = true
+	lines = d.render(tasks, now.Add(2*time.Second))
+	assert.Contains(t, lines[0], "rate:10 req/s done")
+}
+
+func TestRenderRefreshInPlace(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	d := New(buffer)
+
+	d.Render([]TaskProgress{{Name: "t1", Total: 10}})
+	assert.False(t, strings.Contains(buffer.String(), "\033[1A"))
+
+	buffer.Reset()
+	d.Render([]TaskProgress{{Name: "t1", Total: 10, Processed: 5}})
+	assert.True(t, strings.HasPrefix(buffer.String(), "\033[1A\033[J"))
+}
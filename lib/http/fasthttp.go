@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +24,16 @@ import (
 var client *fasthttp.Client
 var clientOnce sync.Once
 
+// Response 响应信息
+type Response struct {
+	// StatusCode 状态码
+	StatusCode int
+	// Header 响应头，key 为规范化之后的名字
+	Header http.Header
+	// Body 原始响应体
+	Body []byte
+}
+
 type Entity struct {
 	Name string
 	Id   int
@@ -175,22 +186,9 @@ func PostTimeOut(ctx context.Context, requestUrl string, params interface{}, hea
 
 	// header
 	req.Header.SetMethod(fasthttp.MethodPost)
-	req.Header.SetContentType(constant.ContentTypeJson)
-	if headers[constant.HeaderKeyContextType] == constant.ContentTypeForm {
-		req.Header.SetContentType(constant.ContentTypeForm)
-		if params != nil {
-			values, err := url.ParseQuery(params.(string))
-			if err != nil {
-				return err
-			}
-			req.SetBodyString(values.Encode())
-		}
-	} else {
-		marshal, err := sonic.Marshal(params)
-		if err != nil {
-			return err
-		}
-		req.SetBody(marshal)
+	err = setBody(req, params, headers)
+	if err != nil {
+		return err
 	}
 
 	resp := fasthttp.AcquireResponse()
@@ -205,17 +203,110 @@ func PostTimeOut(ctx context.Context, requestUrl string, params interface{}, hea
 	return nil
 }
 
-func doTimeOut(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, timeOut time.Duration, result interface{}) error {
-	logger.Debug(ctx, "http_DoTimeOut", zap.Any("request", req), zap.Any("response", resp), zap.Duration("timeOut", timeOut))
+// Send 发送请求并返回完整的响应信息，不需要设置超时时间
+func Send(ctx context.Context, method string, requestUrl string, params interface{}, headers map[string]string) (*Response, error) {
+	return SendTimeOut(ctx, method, requestUrl, params, headers, time.Duration(0))
+}
 
-	var err error
+// SendTimeOut 发送请求并返回完整的响应信息，需要设置超时时间
+//
+// 不校验状态码也不反序列化响应体，由调用方自行处理
+func SendTimeOut(ctx context.Context, method string, requestUrl string, params interface{}, headers map[string]string, timeOut time.Duration) (*Response, error) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
 
-	if timeOut <= 0 {
-		err = client.Do(req, resp)
-	} else {
-		err = client.DoTimeout(req, resp, timeOut)
+	err := initRequest(req, method, requestUrl, params, headers)
+	if err != nil {
+		return nil, err
 	}
 
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	err = do(ctx, req, resp, timeOut)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{
+		StatusCode: resp.StatusCode(),
+		Header:     http.Header{},
+		Body:       append([]byte(nil), resp.Body()...),
+	}
+
+	resp.Header.VisitAll(func(key, value []byte) {
+		response.Header.Add(string(key), string(value))
+	})
+
+	return response, nil
+}
+
+// initRequest 设置请求地址、请求方法、请求头和请求体
+func initRequest(req *fasthttp.Request, method string, requestUrl string, params interface{}, headers map[string]string) error {
+	//解析验证url
+	_, err := url.Parse(requestUrl)
+	if err != nil {
+		return err
+	}
+
+	req.SetRequestURI(requestUrl)
+
+	// 添加请求头
+	for key, value := range headers {
+		req.Header.Add(key, value)
+	}
+
+	req.Header.SetMethod(method)
+	if params != nil {
+		return setBody(req, params, headers)
+	}
+
+	return nil
+}
+
+// ContentType 请求头中的 Content-Type，请求头名称不区分大小写，只返回媒体类型，不包含 charset 等参数
+func ContentType(headers map[string]string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, constant.HeaderKeyContextType) {
+			mediaType, _, _ := strings.Cut(value, ";")
+			return strings.ToLower(strings.TrimSpace(mediaType))
+		}
+	}
+
+	return ""
+}
+
+// setBody 设置请求体，Form 表单请求使用 URL 参数形式，其余情况使用 JSON
+//
+// Form 表单请求的参数必须是 URL 参数格式的字符串
+func setBody(req *fasthttp.Request, params interface{}, headers map[string]string) error {
+	if ContentType(headers) != constant.ContentTypeForm {
+		req.Header.SetContentType(constant.ContentTypeJson)
+		marshal, err := sonic.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.SetBody(marshal)
+		return nil
+	}
+
+	req.Header.SetContentType(constant.ContentTypeForm)
+	switch value := params.(type) {
+	case string:
+		values, err := url.ParseQuery(value)
+		if err != nil {
+			return err
+		}
+		req.SetBodyString(values.Encode())
+	default:
+		return fmt.Errorf("form body should be url encoded string, got %T", params)
+	}
+
+	return nil
+}
+
+func doTimeOut(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, timeOut time.Duration, result interface{}) error {
+	err := do(ctx, req, resp, timeOut)
 	if err != nil {
 		return err
 	}
@@ -236,3 +327,14 @@ func doTimeOut(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Respon
 
 	return nil
 }
+
+// do 发送请求，超时时间小于等于 0 时不设置超时时间
+func do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response, timeOut time.Duration) error {
+	logger.Debug(ctx, "http_DoTimeOut", zap.Any("request", req), zap.Any("response", resp), zap.Duration("timeOut", timeOut))
+
+	if timeOut <= 0 {
+		return client.Do(req, resp)
+	}
+
+	return client.DoTimeout(req, resp, timeOut)
+}
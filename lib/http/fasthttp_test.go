@@ -2,6 +2,7 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"http-diff/lib/logger"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func TestGet(t *testing.T) {
@@ -113,3 +115,73 @@ func TestPostJson(t *testing.T) {
 
 	assert.Nil(t, err)
 }
+
+func TestSendPutJson(t *testing.T) {
+	configStruct := &config.Configs{}
+	err := config.Init("./data/config.toml", configStruct)
+	assert.Nil(t, err)
+
+	logger.Init("TestNacos", configStruct.LoggerConfig)
+	Init(configStruct.FastHttp)
+
+	type request struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age"`
+		Friends []string `json:"friends"`
+	}
+
+	type responseBody struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Success string `json:"success"`
+		} `json:"data"`
+		TraceId string `json:"traceId"`
+	}
+
+	background := context.Background()
+	params := request{
+		Name:    "test",
+		Age:     18,
+		Friends: []string{"name1", "name2"},
+	}
+
+	headers := make(map[string]string)
+	headers[constant.HeaderKeyContextType] = constant.ContentTypeJson
+	response, err := SendTimeOut(background, constant.PUT, "http://127.0.0.1:8080/json", params, headers, time.Second)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 200, response.StatusCode)
+
+	result := &responseBody{}
+	err = json.Unmarshal(response.Body, result)
+	assert.Nil(t, err)
+}
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, "", ContentType(nil))
+	assert.Equal(t, constant.ContentTypeForm, ContentType(map[string]string{"content-type": "application/x-www-form-urlencoded; charset=utf-8"}))
+	assert.Equal(t, constant.ContentTypeJson, ContentType(map[string]string{constant.HeaderKeyContextType: "Application/JSON"}))
+}
+
+func TestSetBody(t *testing.T) {
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+
+	formHeaders := map[string]string{"content-type": constant.ContentTypeForm}
+
+	err := setBody(req, "name=test&age=18", formHeaders)
+	assert.Nil(t, err)
+	assert.Equal(t, constant.ContentTypeForm, string(req.Header.ContentType()))
+	assert.Equal(t, "age=18&name=test", string(req.Body()))
+
+	// Form 表单请求的参数不是字符串时返回错误
+	err = setBody(req, json.RawMessage(`{"name":"test"}`), formHeaders)
+	assert.NotNil(t, err)
+
+	err = setBody(req, json.RawMessage(`{"name":"test"}`), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, constant.ContentTypeJson, string(req.Header.ContentType()))
+	assert.Equal(t, `{"name":"test"}`, string(req.Body()))
+}
@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// accessLogPattern nginx 和 Apache 的 combined、common 格式日志，只解析请求行
+//
+// 例如：127.0.0.1 - - [10/Oct/2026:13:55:36 +0800] "GET /api/user?id=1 HTTP/1.1" 200 612 "-" "curl/8.0"
+var accessLogPattern = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "([A-Za-z]+) (\S+)(?: [^"]*)?" \d{3} `)
+
+// readAccessLog 读取访问日志，日志中没有请求头和请求体，无法解析的行会被跳过
+func readAccessLog(reader io.Reader, handle func(request *Request) error) (int, error) {
+	maxLineSize := 1024 * 1024
+	buffer := make([]byte, 0, maxLineSize)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(buffer, maxLineSize)
+
+	skipped := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		matches := accessLogPattern.FindStringSubmatch(line)
+		if matches == nil {
+			skipped++
+			continue
+		}
+
+		request, err := newRequest(matches[1], matches[2])
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		if err = handle(request); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, scanner.Err()
+}
@@ -0,0 +1,29 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadAccessLog(t *testing.T) {
+	content := `127.0.0.1 - - [10/Oct/2026:13:55:36 +0800] "GET /api/user?id=1 HTTP/1.1" 200 612 "-" "curl/8.0"
+10.0.0.1 - frank [10/Oct/2026:13:55:37 +0800] "post /api/order HTTP/1.1" 201 0
+10.0.0.1 - - [10/Oct/2026:13:55:38 +0800] "\x16\x03\x01" 400 157 "-" "-"
+
+broken line
+`
+	requests := make([]*Request, 0)
+	skipped, err := Read(FormatAccessLog, strings.NewReader(content), func(request *Request) error {
+		requests = append(requests, request)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, 2, len(requests))
+
+	assert.Equal(t, &Request{Method: "GET", Path: "/api/user", RawQuery: "id=1", Headers: map[string]string{}}, requests[0])
+	assert.Equal(t, "POST", requests[1].Method)
+	assert.Equal(t, "/api/order", requests[1].Path)
+}
@@ -0,0 +1,407 @@
+package importer
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// curlValueOptions 需要参数的 curl 选项，不在其中的选项当作开关处理
+var curlValueOptions = newOptionSet(
+	"-X", "--request", "-H", "--header", "-d", "--data", "--data-raw", "--data-binary",
+	"--data-ascii", "--data-urlencode", "--json", "-b", "--cookie", "-A", "--user-agent", "-e",
+	"--referer", "-u", "--user", "--url", "-o", "--output", "-x", "--proxy", "-m", "--max-time",
+	"--connect-timeout", "-w", "--write-out", "-F", "--form", "-T", "--upload-file", "-c",
+	"--cookie-jar", "-E", "--cert", "--key", "--cacert", "-r", "--range", "-K", "--config",
+	"--resolve", "--retry", "--limit-rate", "--interface",
+)
+
+func newOptionSet(options ...string) map[string]struct{} {
+	result := make(map[string]struct{}, len(options))
+	for _, option := range options {
+		result[option] = struct{}{}
+	}
+
+	return result
+}
+
+// readCurl 读取 curl 命令，每个命令一行，行尾的反斜杠表示命令在下一行继续
+//
+// 支持浏览器 "Copy as cURL (bash)" 导出的命令，无法解析的命令会被跳过
+func readCurl(reader io.Reader, handle func(request *Request) error) (int, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	commands, err := splitShellCommands(string(content))
+	if err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, command := range commands {
+		// 忽略注释和其它命令
+		if len(command) == 0 || path.Base(command[0]) != "curl" {
+			if len(command) > 0 && !strings.HasPrefix(command[0], "#") {
+				skipped++
+			}
+			continue
+		}
+
+		request, err := parseCurl(command[1:])
+		if err != nil {
+			skipped++
+			continue
+		}
+
+		if err = handle(request); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
+
+// parseCurl 解析 curl 命令的参数
+func parseCurl(args []string) (*Request, error) {
+	method := ""
+	rawUrl := ""
+	headers := make([][2]string, 0)
+	data := make([]string, 0)
+	dataInQuery := false
+	isJson := false
+
+	for index := 0; index < len(args); index++ {
+		arg := args[index]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if rawUrl == "" {
+				rawUrl = arg
+			}
+			continue
+		}
+
+		option, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			// --request=POST
+			if before, after, found := strings.Cut(arg, "="); found {
+				option, value, hasValue = before, after, true
+			}
+		} else if len(arg) > 2 {
+			// -XPOST、-sS 等多个短选项写在一起的形式，只处理第一个需要参数的选项
+			option = ""
+			for i := 1; i < len(arg); i++ {
+				if _, ok := curlValueOptions["-"+arg[i:i+1]]; ok {
+					option = "-" + arg[i:i+1]
+					if i+1 < len(arg) {
+						value, hasValue = arg[i+1:], true
+					}
+					break
+				}
+			}
+
+			if option == "" {
+				applyCurlSwitch(arg, &method, &dataInQuery)
+				continue
+			}
+		}
+
+		if _, ok := curlValueOptions[option]; !ok {
+			applyCurlSwitch(option, &method, &dataInQuery)
+			continue
+		}
+
+		if !hasValue {
+			if index+1 >= len(args) {
+				return nil, errors.New("curl option " + option + " requires a value")
+			}
+			index++
+			value = args[index]
+		}
+
+		switch option {
+		case "-X", "--request":
+			method = value
+		case "-H", "--header":
+			name, headerValue, _ := strings.Cut(value, ":")
+			headers = append(headers, [2]string{name, headerValue})
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw":
+			if strings.HasPrefix(value, "@") && option != "--data-raw" {
+				return nil, errors.New("curl data from file is not supported: " + value)
+			}
+			data = append(data, value)
+		case "--data-urlencode":
+			data = append(data, curlUrlEncode(value))
+		case "--json":
+			data = append(data, value)
+			isJson = true
+		case "-b", "--cookie":
+			// 不包含 = 时是 cookie 文件
+			if strings.Contains(value, "=") {
+				headers = append(headers, [2]string{"Cookie", value})
+			}
+		case "-A", "--user-agent":
+			headers = append(headers, [2]string{"User-Agent", value})
+		case "-e", "--referer":
+			headers = append(headers, [2]string{"Referer", value})
+		case "-u", "--user":
+			headers = append(headers, [2]string{"Authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(value))})
+		case "--url":
+			rawUrl = value
+		case "-F", "--form", "-T", "--upload-file":
+			return nil, errors.New("curl option " + option + " is not supported")
+		}
+	}
+
+	if rawUrl == "" {
+		return nil, errors.New("curl command has no url")
+	}
+
+	// curl 允许省略协议
+	if !strings.Contains(rawUrl, "://") {
+		rawUrl = "http://" + rawUrl
+	}
+
+	body := strings.Join(data, "&")
+	if method == "" {
+		method = "GET"
+		if body != "" && !dataInQuery {
+			method = "POST"
+		}
+	}
+
+	request, err := newRequest(method, rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range headers {
+		request.addHeader(header[0], header[1])
+	}
+
+	// -G 把数据作为查询参数
+	if dataInQuery && body != "" {
+		if request.RawQuery != "" {
+			request.RawQuery += "&"
+		}
+		request.RawQuery += body
+		body = ""
+	}
+
+	request.Body = body
+	// 和 curl 的默认行为一致，-d 默认为表单，--json 默认为 JSON
+	if body != "" && !request.hasHeader("Content-Type") {
+		contentType := "application/x-www-form-urlencoded"
+		if isJson {
+			contentType = "application/json"
+		}
+		request.addHeader("Content-Type", contentType)
+	}
+
+	return request, nil
+}
+
+// applyCurlSwitch 处理不需要参数的选项，只关心会影响请求的选项
+func applyCurlSwitch(option string, method *string, dataInQuery *bool) {
+	switch {
+	case option == "--get" || (!strings.HasPrefix(option, "--") && strings.Contains(option, "G")):
+		*dataInQuery = true
+	case option == "--head" || (!strings.HasPrefix(option, "--") && strings.Contains(option, "I")):
+		*method = "HEAD"
+	}
+}
+
+// curlUrlEncode 按 --data-urlencode 的规则编码，name=content 时只编码 content
+func curlUrlEncode(value string) string {
+	if name, content, found := strings.Cut(value, "="); found {
+		if name == "" {
+			return url.QueryEscape(content)
+		}
+		return name + "=" + url.QueryEscape(content)
+	}
+
+	return url.QueryEscape(value)
+}
+
+// splitShellCommands 按 bash 的规则把文本拆分为多个命令，每个命令是参数列表
+//
+// 支持单引号、双引号、$'...' 和反斜杠转义，没有被引号包含的换行表示命令结束
+func splitShellCommands(content string) ([][]string, error) {
+	commands := make([][]string, 0)
+	command := make([]string, 0)
+	word := &strings.Builder{}
+	inWord := false
+
+	endWord := func() {
+		if inWord {
+			command = append(command, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(command) > 0 {
+			commands = append(commands, command)
+			command = make([]string, 0)
+		}
+	}
+
+	runes := []rune(content)
+	for index := 0; index < len(runes); index++ {
+		char := runes[index]
+
+		switch {
+		case char == '\n' || char == ';':
+			endCommand()
+		case char == ' ' || char == '\t' || char == '\r':
+			endWord()
+		case char == '#' && !inWord:
+			// 注释到行尾结束
+			for index < len(runes) && runes[index] != '\n' {
+				index++
+			}
+			endCommand()
+		case char == '\\':
+			if index+1 < len(runes) {
+				index++
+				// 行尾的反斜杠表示命令在下一行继续
+				if runes[index] == '\r' && index+1 < len(runes) && runes[index+1] == '\n' {
+					index++
+				}
+				if runes[index] != '\n' {
+					word.WriteRune(runes[index])
+					inWord = true
+				}
+			}
+		case char == '\'':
+			end := indexRune(runes, index+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(string(runes[index+1 : end]))
+			inWord = true
+			index = end
+		case char == '$' && index+1 < len(runes) && runes[index+1] == '\'':
+			end, err := readAnsiCQuote(runes, index+2, word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			index = end
+		case char == '"':
+			end, err := readDoubleQuote(runes, index+1, word)
+			if err != nil {
+				return nil, err
+			}
+			inWord = true
+			index = end
+		default:
+			word.WriteRune(char)
+			inWord = true
+		}
+	}
+	endCommand()
+
+	return commands, nil
+}
+
+func indexRune(runes []rune, start int, target rune) int {
+	for index := start; index < len(runes); index++ {
+		if runes[index] == target {
+			return index
+		}
+	}
+
+	return -1
+}
+
+// readDoubleQuote 读取双引号中的内容，反斜杠只转义 $ ` " \ 和换行，返回结束引号的下标
+func readDoubleQuote(runes []rune, start int, word *strings.Builder) (int, error) {
+	for index := start; index < len(runes); index++ {
+		char := runes[index]
+		switch char {
+		case '"':
+			return index, nil
+		case '\\':
+			if index+1 < len(runes) {
+				next := runes[index+1]
+				switch next {
+				case '$', '`', '"', '\\':
+					word.WriteRune(next)
+					index++
+					continue
+				case '\n':
+					index++
+					continue
+				}
+			}
+			word.WriteRune(char)
+		default:
+			word.WriteRune(char)
+		}
+	}
+
+	return 0, errors.New("unterminated double quote")
+}
+
+// readAnsiCQuote 读取 $'...' 中的内容，支持常用的转义字符，返回结束引号的下标
+func readAnsiCQuote(runes []rune, start int, word *strings.Builder) (int, error) {
+	for index := start; index < len(runes); index++ {
+		char := runes[index]
+		if char == '\'' {
+			return index, nil
+		}
+
+		if char != '\\' || index+1 >= len(runes) {
+			word.WriteRune(char)
+			continue
+		}
+
+		index++
+		switch next := runes[index]; next {
+		case 'n':
+			word.WriteRune('\n')
+		case 't':
+			word.WriteRune('\t')
+		case 'r':
+			word.WriteRune('\r')
+		case '\\', '\'', '"', '?':
+			word.WriteRune(next)
+		case 'x', 'u', 'U':
+			// \xHH、\uHHHH、\UHHHHHHHH
+			size := map[rune]int{'x': 2, 'u': 4, 'U': 8}[next]
+			end := index + 1
+			for end < len(runes) && end < index+1+size && isHex(runes[end]) {
+				end++
+			}
+			if end == index+1 {
+				word.WriteRune('\\')
+				word.WriteRune(next)
+				continue
+			}
+
+			code, _ := strconv.ParseUint(string(runes[index+1:end]), 16, 32)
+			if next == 'x' {
+				word.WriteByte(byte(code))
+			} else {
+				word.WriteRune(rune(code))
+			}
+			index = end - 1
+		default:
+			word.WriteRune('\\')
+			word.WriteRune(next)
+		}
+	}
+
+	return 0, errors.New("unterminated ansi-c quote")
+}
+
+func isHex(char rune) bool {
+	return (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
+}
@@ -0,0 +1,66 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitShellCommands(t *testing.T) {
+	commands, err := splitShellCommands(`curl 'http://a/b' \
+  -H "X-A: \"1\"" --data-raw $'{"a":"中\n"}'
+# comment
+curl http://c/d?x=1 -d a\ b
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"curl", "http://a/b", "-H", `X-A: "1"`, "--data-raw", "{\"a\":\"中\n\"}"},
+		{"curl", "http://c/d?x=1", "-d", "a b"},
+	}, commands)
+
+	_, err = splitShellCommands(`curl 'http://a`)
+	assert.NotNil(t, err)
+}
+
+func TestReadCurl(t *testing.T) {
+	content := `curl 'https://example.com/api/user?id=1' \
+  -H 'Accept-Encoding: gzip' \
+  -H 'Content-Type: application/json' \
+  -H 'Cookie: a=1' -b 'b=2' \
+  --data-raw '{"name":"test"}' \
+  --compressed
+curl -XPUT example.com/api/user/2 -d 'a=1' -d 'b=2'
+curl -G http://example.com/search --data-urlencode 'q=a b'
+curl -I http://example.com/health
+curl -F 'file=@a.txt' http://example.com/upload
+ls -al
+`
+	requests := make([]*Request, 0)
+	skipped, err := Read(FormatCurl, strings.NewReader(content), func(request *Request) error {
+		requests = append(requests, request)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, skipped)
+	assert.Equal(t, 4, len(requests))
+
+	assert.Equal(t, &Request{
+		Method:   "POST",
+		Path:     "/api/user",
+		RawQuery: "id=1",
+		Headers:  map[string]string{"Content-Type": "application/json", "Cookie": "a=1; b=2"},
+		Body:     `{"name":"test"}`,
+	}, requests[0])
+
+	assert.Equal(t, "PUT", requests[1].Method)
+	assert.Equal(t, "/api/user/2", requests[1].Path)
+	assert.Equal(t, "a=1&b=2", requests[1].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", requests[1].Headers["Content-Type"])
+
+	assert.Equal(t, "GET", requests[2].Method)
+	assert.Equal(t, "q=a+b", requests[2].RawQuery)
+	assert.Equal(t, "", requests[2].Body)
+
+	assert.Equal(t, "HEAD", requests[3].Method)
+}
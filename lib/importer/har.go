@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/bytedance/sonic"
+)
+
+// har HAR 文件中需要的字段
+type har struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method  string      `json:"method"`
+				Url     string      `json:"url"`
+				Headers []harHeader `json:"headers"`
+				// PostData 请求体，表单请求可能只有 params 没有 text
+				PostData *struct {
+					MimeType string      `json:"mimeType"`
+					Text     string      `json:"text"`
+					Params   []harHeader `json:"params"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// harHeader HAR 中的请求头和表单参数都是 name、value 的形式
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// readHar 读取浏览器或抓包工具导出的 HAR 文件
+func readHar(reader io.Reader, handle func(request *Request) error) (int, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	harFile := &har{}
+	if err = sonic.Unmarshal(content, harFile); err != nil {
+		return 0, err
+	}
+
+	skipped := 0
+	for _, entry := range harFile.Log.Entries {
+		request, err := newRequest(entry.Request.Method, entry.Request.Url)
+		if err != nil || request.Method == "" {
+			skipped++
+			continue
+		}
+
+		for _, header := range entry.Request.Headers {
+			request.addHeader(header.Name, header.Value)
+		}
+
+		if postData := entry.Request.PostData; postData != nil {
+			request.Body = postData.Text
+			if request.Body == "" && len(postData.Params) > 0 {
+				values := make([]string, 0, len(postData.Params))
+				for _, param := range postData.Params {
+					values = append(values, url.QueryEscape(param.Name)+"="+url.QueryEscape(param.Value))
+				}
+				request.Body = strings.Join(values, "&")
+			}
+
+			if postData.MimeType != "" && !request.hasHeader("Content-Type") {
+				request.addHeader("Content-Type", postData.MimeType)
+			}
+		}
+
+		if err = handle(request); err != nil {
+			return skipped, err
+		}
+	}
+
+	return skipped, nil
+}
@@ -0,0 +1,41 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadHar(t *testing.T) {
+	content := `{"log":{"entries":[
+{"request":{"method":"post","url":"https://example.com/api/user?id=1&b=%20","headers":[{"name":":authority","value":"example.com"},{"name":"host","value":"example.com"},{"name":"x-trace","value":"1"},{"name":"X-Trace","value":"2"}],"postData":{"mimeType":"application/json","text":"{\"a\":1}"}}},
+{"request":{"method":"POST","url":"https://example.com/form","headers":[],"postData":{"mimeType":"application/x-www-form-urlencoded","params":[{"name":"a","value":"1 2"},{"name":"b","value":"3"}]}}},
+{"request":{"method":"GET","url":"://bad","headers":[]}}
+]}}`
+
+	requests := make([]*Request, 0)
+	skipped, err := Read(FormatHar, strings.NewReader(content), func(request *Request) error {
+		requests = append(requests, request)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, skipped)
+	assert.Equal(t, 2, len(requests))
+
+	assert.Equal(t, &Request{
+		Method:   "POST",
+		Path:     "/api/user",
+		RawQuery: "id=1&b=%20",
+		Headers:  map[string]string{"X-Trace": "1, 2", "Content-Type": "application/json"},
+		Body:     `{"a":1}`,
+	}, requests[0])
+
+	assert.Equal(t, "a=1+2&b=3", requests[1].Body)
+	assert.Equal(t, "application/x-www-form-urlencoded", requests[1].Headers["Content-Type"])
+}
+
+func TestReadUnsupportedFormat(t *testing.T) {
+	_, err := Read("pcap", strings.NewReader(""), func(request *Request) error { return nil })
+	assert.NotNil(t, err)
+}
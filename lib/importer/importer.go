@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// 支持导入的格式
+const (
+	FormatHar       = "har"
+	FormatCurl      = "curl"
+	FormatAccessLog = "access_log"
+)
+
+// ignoredHeaders 导入时丢弃的请求头，由发送请求的客户端生成，原样回放会导致请求出错
+var ignoredHeaders = map[string]struct{}{
+	"Host":              {},
+	"Content-Length":    {},
+	"Connection":        {},
+	"Accept-Encoding":   {},
+	"Transfer-Encoding": {},
+}
+
+// Request 从抓包数据中解析出的请求
+type Request struct {
+	Method string
+	// Path 请求路径，不包含查询参数
+	Path string
+	// RawQuery 原始的查询参数，没有问号
+	RawQuery string
+	// Headers 请求头，同名的请求头用逗号合并
+	Headers map[string]string
+	// Body 原始请求体
+	Body string
+}
+
+// Read 按格式读取抓包数据，每解析出一个请求调用一次 handle，返回跳过的无法解析的条目数量
+func Read(format string, reader io.Reader, handle func(request *Request) error) (int, error) {
+	switch format {
+	case FormatHar:
+		return readHar(reader, handle)
+	case FormatCurl:
+		return readCurl(reader, handle)
+	case FormatAccessLog:
+		return readAccessLog(reader, handle)
+	default:
+		return 0, errors.New("unsupported import format: " + format)
+	}
+}
+
+// newRequest 根据请求方法和地址创建请求，地址可以是完整的 URL 或者以 / 开头的路径
+func newRequest(method string, rawUrl string) (*Request, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	path := parsedUrl.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return &Request{
+		Method:   strings.ToUpper(method),
+		Path:     path,
+		RawQuery: parsedUrl.RawQuery,
+		Headers:  make(map[string]string),
+	}, nil
+}
+
+// addHeader 添加请求头，忽略 HTTP/2 的伪请求头和 ignoredHeaders 中的请求头
+func (r *Request) addHeader(name string, value string) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.HasPrefix(name, ":") {
+		return
+	}
+
+	name = http.CanonicalHeaderKey(name)
+	if _, ok := ignoredHeaders[name]; ok {
+		return
+	}
+
+	value = strings.TrimSpace(value)
+	if existing, ok := r.Headers[name]; ok {
+		separator := ", "
+		if name == "Cookie" {
+			separator = "; "
+		}
+		value = existing + separator + value
+	}
+
+	r.Headers[name] = value
+}
+
+// hasHeader 判断请求头是否存在，不区分大小写
+func (r *Request) hasHeader(name string) bool {
+	_, ok := r.Headers[http.CanonicalHeaderKey(name)]
+	return ok
+}
@@ -0,0 +1,396 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 指标类型
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// DefaultBuckets 默认的耗时分布桶，单位秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 默认的指标注册中心
+var Default = NewRegistry()
+
+// family 同一个名字的一组指标
+type family interface {
+	write(builder *strings.Builder)
+}
+
+// Registry 指标注册中心，按 Prometheus 文本格式输出所有指标
+type Registry struct {
+	lock     sync.Mutex
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f family) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.families = append(r.families, f)
+}
+
+// WriteTo 按 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteTo(writer io.Writer) (int64, error) {
+	r.lock.Lock()
+	families := append([]family(nil), r.families...)
+	r.lock.Unlock()
+
+	builder := &strings.Builder{}
+	for _, f := range families {
+		f.write(builder)
+	}
+
+	n, err := io.WriteString(writer, builder.String())
+	return int64(n), err
+}
+
+// Handler 输出指标的 HTTP 处理函数
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(writer)
+	})
+}
+
+// Serve 启动 HTTP 服务输出指标，上下文取消时关闭服务
+func (r *Registry) Serve(ctx context.Context, address string, path string) error {
+	mux := http.NewServeMux()
+	mux.Handle(path, r.Handler())
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancelFunc := context.WithTimeout(context.Background(), time.Second)
+		defer cancelFunc()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// metricVec 带标签的指标的公共部分
+type metricVec struct {
+	lock       sync.Mutex
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+}
+
+func (m *metricVec) writeHeader(builder *strings.Builder) {
+	builder.WriteString("# HELP " + m.name + " " + escapeHelp(m.help) + "\n")
+	builder.WriteString("# TYPE " + m.name + " " + m.metricType + "\n")
+}
+
+// labelKey 标签值组成的 key，用于区分同一个指标的不同序列
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// formatLabels 格式化标签，extraName 和 extraValue 不为空时追加在最后，用于直方图的 le 标签
+func formatLabels(labelNames []string, labelValues []string, extraName string, extraValue string) string {
+	if len(labelNames) == 0 && extraName == "" {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labelNames)+1)
+	for index, name := range labelNames {
+		parts = append(parts, name+`="`+escapeLabelValue(labelValues[index])+`"`)
+	}
+
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// sortedKeys 按 key 排序，保证输出顺序稳定
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Value 计数器或仪表盘的一个序列
+type Value struct {
+	lock        sync.Mutex
+	labelValues []string
+	value       float64
+}
+
+// Add 增加指定的值
+func (v *Value) Add(delta float64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.value += delta
+}
+
+// Inc 加 1
+func (v *Value) Inc() {
+	v.Add(1)
+}
+
+// Dec 减 1，只用于仪表盘
+func (v *Value) Dec() {
+	v.Add(-1)
+}
+
+// Set 设置为指定的值，只用于仪表盘
+func (v *Value) Set(value float64) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.value = value
+}
+
+func (v *Value) get() float64 {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	return v.value
+}
+
+// ValueVec 带标签的计数器或仪表盘
+type ValueVec struct {
+	metricVec
+	values map[string]*Value
+}
+
+// NewCounterVec 创建计数器
+func (r *Registry) NewCounterVec(name string, help string, labelNames ...string) *ValueVec {
+	return r.newValueVec(name, help, typeCounter, labelNames)
+}
+
+// NewGaugeVec 创建仪表盘
+func (r *Registry) NewGaugeVec(name string, help string, labelNames ...string) *ValueVec {
+	return r.newValueVec(name, help, typeGauge, labelNames)
+}
+
+func (r *Registry) newValueVec(name string, help string, metricType string, labelNames []string) *ValueVec {
+	vec := &ValueVec{
+		metricVec: metricVec{name: name, help: help, metricType: metricType, labelNames: labelNames},
+		values:    make(map[string]*Value),
+	}
+	r.register(vec)
+
+	return vec
+}
+
+// WithLabelValues 获取标签值对应的序列，不存在时创建
+func (v *ValueVec) WithLabelValues(labelValues ...string) *Value {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	key := labelKey(labelValues)
+	value, ok := v.values[key]
+	if !ok {
+		value = &Value{labelValues: append([]string(nil), labelValues...)}
+		v.values[key] = value
+	}
+
+	return value
+}
+
+func (v *ValueVec) write(builder *strings.Builder) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if len(v.values) == 0 {
+		return
+	}
+
+	v.writeHeader(builder)
+	for _, key := range sortedKeys(v.values) {
+		value := v.values[key]
+		builder.WriteString(v.name + formatLabels(v.labelNames, value.labelValues, "", "") + " " + formatFloat(value.get()) + "\n")
+	}
+}
+
+// funcValue 输出时通过回调函数获取值的序列
+type funcValue struct {
+	labelValues []string
+	getter      func() float64
+}
+
+// FuncVec 输出时通过回调函数获取值的计数器或仪表盘，适合已经在别处统计的数据
+type FuncVec struct {
+	metricVec
+	values map[string]*funcValue
+}
+
+// NewCounterFuncVec 创建通过回调函数获取值的计数器
+func (r *Registry) NewCounterFuncVec(name string, help string, labelNames ...string) *FuncVec {
+	return r.newFuncVec(name, help, typeCounter, labelNames)
+}
+
+// NewGaugeFuncVec 创建通过回调函数获取值的仪表盘
+func (r *Registry) NewGaugeFuncVec(name string, help string, labelNames ...string) *FuncVec {
+	return r.newFuncVec(name, help, typeGauge, labelNames)
+}
+
+func (r *Registry) newFuncVec(name string, help string, metricType string, labelNames []string) *FuncVec {
+	vec := &FuncVec{
+		metricVec: metricVec{name: name, help: help, metricType: metricType, labelNames: labelNames},
+		values:    make(map[string]*funcValue),
+	}
+	r.register(vec)
+
+	return vec
+}
+
+// Set 设置标签值对应的回调函数，已经存在时覆盖
+func (v *FuncVec) Set(getter func() float64, labelValues ...string) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.values[labelKey(labelValues)] = &funcValue{labelValues: append([]string(nil), labelValues...), getter: getter}
+}
+
+func (v *FuncVec) write(builder *strings.Builder) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if len(v.values) == 0 {
+		return
+	}
+
+	v.writeHeader(builder)
+	for _, key := range sortedKeys(v.values) {
+		value := v.values[key]
+		builder.WriteString(v.name + formatLabels(v.labelNames, value.labelValues, "", "") + " " + formatFloat(value.getter()) + "\n")
+	}
+}
+
+// Histogram 直方图的一个序列
+type Histogram struct {
+	lock        sync.Mutex
+	labelValues []string
+	buckets     []float64
+	// counts 每个桶的数量，不是累计值
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe 记录一个值
+func (h *Histogram) Observe(value float64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	index := sort.SearchFloat64s(h.buckets, value)
+	if index < len(h.counts) {
+		h.counts[index]++
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	metricVec
+	buckets    []float64
+	histograms map[string]*Histogram
+}
+
+// NewHistogramVec 创建直方图，buckets 为每个桶的上限，需要从小到大排列
+func (r *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	vec := &HistogramVec{
+		metricVec:  metricVec{name: name, help: help, metricType: typeHistogram, labelNames: labelNames},
+		buckets:    buckets,
+		histograms: make(map[string]*Histogram),
+	}
+	r.register(vec)
+
+	return vec
+}
+
+// WithLabelValues 获取标签值对应的序列，不存在时创建
+func (v *HistogramVec) WithLabelValues(labelValues ...string) *Histogram {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	key := labelKey(labelValues)
+	histogram, ok := v.histograms[key]
+	if !ok {
+		histogram = &Histogram{
+			labelValues: append([]string(nil), labelValues...),
+			buckets:     v.buckets,
+			counts:      make([]uint64, len(v.buckets)),
+		}
+		v.histograms[key] = histogram
+	}
+
+	return histogram
+}
+
+func (v *HistogramVec) write(builder *strings.Builder) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if len(v.histograms) == 0 {
+		return
+	}
+
+	v.writeHeader(builder)
+	for _, key := range sortedKeys(v.histograms) {
+		histogram := v.histograms[key]
+
+		histogram.lock.Lock()
+		var cumulative uint64
+		for index, upper := range v.buckets {
+			cumulative += histogram.counts[index]
+			builder.WriteString(v.name + "_bucket" + formatLabels(v.labelNames, histogram.labelValues, "le", formatFloat(upper)) + " " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		builder.WriteString(v.name + "_bucket" + formatLabels(v.labelNames, histogram.labelValues, "le", "+Inf") + " " + strconv.FormatUint(histogram.count, 10) + "\n")
+		builder.WriteString(v.name + "_sum" + formatLabels(v.labelNames, histogram.labelValues, "", "") + " " + formatFloat(histogram.sum) + "\n")
+		builder.WriteString(v.name + "_count" + formatLabels(v.labelNames, histogram.labelValues, "", "") + " " + strconv.FormatUint(histogram.count, 10) + "\n")
+		histogram.lock.Unlock()
+	}
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueVec(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_requests_total", "Total requests.", "task", "result")
+	gauge := registry.NewGaugeVec("test_in_flight", "In flight requests.")
+
+	counter.WithLabelValues("t1", "same").Inc()
+	counter.WithLabelValues("t1", "same").Add(2)
+	counter.WithLabelValues("t\"1", "diff").Inc()
+	gauge.WithLabelValues().Inc()
+	gauge.WithLabelValues().Inc()
+	gauge.WithLabelValues().Dec()
+
+	builder := &strings.Builder{}
+	_, err := registry.WriteTo(builder)
+	assert.Nil(t, err)
+	assert.Equal(t, `# HELP test_requests_total Total requests.
+# TYPE test_requests_total counter
+test_requests_total{task="t\"1",result="diff"} 1
+test_requests_total{task="t1",result="same"} 3
+# HELP test_in_flight In flight requests.
+# TYPE test_in_flight gauge
+test_in_flight 1
+`, builder.String())
+}
+
+func TestFuncVec(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.NewGaugeFuncVec("test_total", "Total.", "task")
+
+	// 没有序列时不输出
+	builder := &strings.Builder{}
+	_, _ = registry.WriteTo(builder)
+	assert.Equal(t, "", builder.String())
+
+	value := 1.0
+	gauge.Set(func() float64 { return value }, "t1")
+	value = 2.5
+
+	_, _ = registry.WriteTo(builder)
+	assert.Contains(t, builder.String(), `test_total{task="t1"} 2.5`)
+}
+
+func TestHistogramVec(t *testing.T) {
+	registry := NewRegistry()
+	histogram := registry.NewHistogramVec("test_duration_seconds", "Duration.", []float64{0.1, 1}, "side")
+
+	histogram.WithLabelValues("a").Observe(0.05)
+	histogram.WithLabelValues("a").Observe(0.5)
+	histogram.WithLabelValues("a").Observe(5)
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{side="a",le="0.1"} 1
+test_duration_seconds_bucket{side="a",le="1"} 2
+test_duration_seconds_bucket{side="a",le="+Inf"} 3
+test_duration_seconds_sum{side="a"} 5.55
+test_duration_seconds_count{side="a"} 3
+`, recorder.Body.String())
+	assert.True(t, strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain"))
+}
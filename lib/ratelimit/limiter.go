@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter 令牌桶限流器，多个协程共享同一个限流器
+//
+// 令牌以 qps 的速度放入桶中，桶中最多存放 burst 个令牌。nil 表示不限流
+type Limiter struct {
+	lock sync.Mutex
+
+	// qps 每秒放入的令牌数量
+	qps float64
+	// burst 桶的容量，允许的最大突发请求数量
+	burst float64
+	// tokens 当前令牌数量，为负数时表示已经被预定的令牌
+	tokens float64
+	// last 上次计算令牌数量的时间
+	last time.Time
+}
+
+// NewLimiter 创建限流器，qps 小于等于 0 时返回 nil 表示不限流，burst 小于 1 时按 1 处理
+func NewLimiter(qps float64, burst int) *Limiter {
+	if qps <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		qps:    qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait 获取一个令牌，没有令牌时等待，上下文取消时返回错误
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	delay := l.reserve(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// 归还没有使用的令牌
+		l.lock.Lock()
+		l.tokens++
+		l.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+// QPS 每秒放入的令牌数量
+func (l *Limiter) QPS() float64 {
+	if l == nil {
+		return 0
+	}
+
+	return l.qps
+}
+
+// Burst 桶的容量
+func (l *Limiter) Burst() int {
+	if l == nil {
+		return 0
+	}
+
+	return int(l.burst)
+}
+
+// reserve 预定一个令牌，返回需要等待的时间
+func (l *Limiter) reserve(now time.Time) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.qps
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.qps * float64(time.Second))
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLimiter(t *testing.T) {
+	assert.Nil(t, NewLimiter(0, 10))
+	assert.Nil(t, NewLimiter(-1, 10))
+
+	limiter := NewLimiter(10, 0)
+	assert.Equal(t, float64(10), limiter.QPS())
+	assert.Equal(t, 1, limiter.Burst())
+
+	var nilLimiter *Limiter
+	assert.Nil(t, nilLimiter.Wait(context.Background()))
+	assert.Equal(t, float64(0), nilLimiter.QPS())
+}
+
+func TestLimiterReserve(t *testing.T) {
+	limiter := NewLimiter(10, 2)
+	now := limiter.last
+
+	// 桶中的令牌可以直接使用
+	assert.Equal(t, time.Duration(0), limiter.reserve(now))
+	assert.Equal(t, time.Duration(0), limiter.reserve(now))
+
+	// 令牌用完之后按照 qps 等待
+	assert.Equal(t, 100*time.Millisecond, limiter.reserve(now))
+	assert.Equal(t, 200*time.Millisecond, limiter.reserve(now))
+
+	// 一秒之后放入 10 个令牌，但是最多只能存放 2 个
+	now = now.Add(time.Second)
+	assert.Equal(t, time.Duration(0), limiter.reserve(now))
+	assert.Equal(t, time.Duration(0), limiter.reserve(now))
+	assert.Equal(t, 100*time.Millisecond, limiter.reserve(now))
+}
+
+func TestLimiterWait(t *testing.T) {
+	limiter := NewLimiter(100, 1)
+
+	start := time.Now()
+	for i := 0; i < 11; i++ {
+		assert.Nil(t, limiter.Wait(context.Background()))
+	}
+
+	// 第一个令牌不需要等待，后面 10 个令牌需要等待 100ms
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestLimiterWaitCancel(t *testing.T) {
+	limiter := NewLimiter(1, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelFunc()
+
+	assert.NotNil(t, limiter.Wait(ctx))
+}
@@ -0,0 +1,313 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"http-diff/util"
+)
+
+// 改写的对象
+const (
+	targetPath   = "path"
+	targetHeader = "header"
+	targetParam  = "param"
+	targetBody   = "body"
+)
+
+// 改写的动作
+const (
+	actionSet     = "set"
+	actionAdd     = "add"
+	actionDel     = "del"
+	actionRename  = "rename"
+	actionMove    = "move"
+	actionReplace = "replace"
+)
+
+// actionArgs 每种改写规则需要的参数数量，参数中的 : 需要写成 \:，最后一个参数可以直接包含 :
+var actionArgs = map[string]int{
+	targetPath + "." + actionReplace: 2,
+	targetHeader + "." + actionSet:   2,
+	targetHeader + "." + actionAdd:   2,
+	targetHeader + "." + actionDel:   1,
+	targetParam + "." + actionSet:    2,
+	targetParam + "." + actionAdd:    2,
+	targetParam + "." + actionDel:    1,
+	targetParam + "." + actionRename: 2,
+	targetBody + "." + actionSet:     2,
+	targetBody + "." + actionDel:     1,
+	targetBody + "." + actionRename:  2,
+	targetBody + "." + actionMove:    2,
+}
+
+// rule 一条改写规则
+type rule struct {
+	action string
+	args   []string
+	// pattern path.replace 的正则表达式
+	pattern *regexp.Regexp
+	// value body.set 的值
+	value interface{}
+}
+
+// Rules 发送请求之前对请求路径、请求头、URL 参数和请求体的改写规则，同一类规则按照配置的顺序执行
+//
+// 规则的格式为 对象.动作:参数[:参数]，参数中的 : 写成 \: 进行转义，nil 表示不改写
+type Rules struct {
+	path    []*rule
+	headers []*rule
+	params  []*rule
+	body    []*rule
+}
+
+// Parse 解析改写规则，没有规则时返回 nil
+//
+//	path.replace:正则表达式:替换内容   替换请求路径，替换内容中可以使用 $1 引用分组，正则表达式中的 : 写成 \:，例如 (?\:v1|v2)
+//	header.set:名称:值                设置请求头，已经存在时覆盖
+//	header.add:名称:值                添加请求头，已经存在时用逗号追加
+//	header.del:名称                   删除请求头
+//	param.set:名称:值                 设置 URL 参数，已经存在时覆盖
+//	param.add:名称:值                 添加 URL 参数，已经存在时追加同名参数
+//	param.del:名称                    删除 URL 参数
+//	param.rename:名称:新名称          重命名 URL 参数
+//	body.set:路径:值                  设置请求体中的字段，值为 JSON，不是合法的 JSON 时作为字符串
+//	body.del:路径                     删除请求体中的字段
+//	body.rename:路径:新名称           重命名请求体中的字段，路径支持 * 和 ..
+//	body.move:路径:新路径             移动请求体中的字段，路径只能匹配一个字段
+func Parse(definitions []string) (*Rules, error) {
+	if len(definitions) == 0 {
+		return nil, nil
+	}
+
+	rules := &Rules{}
+	for _, definition := range definitions {
+		definition = strings.TrimSpace(definition)
+		name, rest, _ := strings.Cut(definition, ":")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		argCount, ok := actionArgs[name]
+		if !ok {
+			return nil, errors.New("rewrite rule is not supported: " + definition)
+		}
+
+		args := util.SplitEscaped(rest, argCount)
+		if len(args) != argCount || strings.TrimSpace(args[0]) == "" {
+			return nil, errors.New("invalid rewrite rule format: " + definition)
+		}
+		args[0] = strings.TrimSpace(args[0])
+
+		target, action, _ := strings.Cut(name, ".")
+		r := &rule{action: action, args: args}
+
+		switch target {
+		case targetPath:
+			pattern, err := regexp.Compile(args[0])
+			if err != nil {
+				return nil, errors.New("invalid rewrite rule pattern: " + definition + ", " + err.Error())
+			}
+			r.pattern = pattern
+			rules.path = append(rules.path, r)
+		case targetHeader:
+			rules.headers = append(rules.headers, r)
+		case targetParam:
+			rules.params = append(rules.params, r)
+		case targetBody:
+			if action == actionSet {
+				r.value = parseBodyValue(args[1])
+			}
+
+			if action == actionRename || action == actionMove {
+				args[1] = strings.TrimSpace(args[1])
+			}
+
+			if err := checkBodyPaths(action, args); err != nil {
+				return nil, errors.New("invalid rewrite rule path: " + definition + ", " + err.Error())
+			}
+
+			rules.body = append(rules.body, r)
+		}
+	}
+
+	return rules, nil
+}
+
+// checkBodyPaths 检查路径表达式是否合法，设置和移动的目标路径不支持 * 和 ..
+func checkBodyPaths(action string, args []string) error {
+	paths := []string{args[0]}
+	targetPath := ""
+	switch action {
+	case actionSet:
+		targetPath = args[0]
+	case actionMove:
+		targetPath = args[1]
+		paths = append(paths, args[1])
+	}
+
+	for _, path := range paths {
+		if _, err := util.FindJsonNodes(map[string]interface{}{}, path); err != nil {
+			return err
+		}
+	}
+
+	if strings.Contains(targetPath, "*") || strings.Contains(targetPath, "..") {
+		return errors.New("target path cannot contain * or ..")
+	}
+
+	return nil
+}
+
+// parseBodyValue 解析 body.set 的值，不是合法的 JSON 时作为字符串
+func parseBodyValue(text string) interface{} {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return text
+	}
+
+	return value
+}
+
+// RewritePath 改写请求路径
+func (r *Rules) RewritePath(path string) string {
+	if r == nil {
+		return path
+	}
+
+	for _, rule := range r.path {
+		path = rule.pattern.ReplaceAllString(path, rule.args[1])
+	}
+
+	return path
+}
+
+// RewriteHeaders 改写请求头，请求头名称不区分大小写
+func (r *Rules) RewriteHeaders(headers map[string]string) {
+	if r == nil {
+		return
+	}
+
+	for _, rule := range r.headers {
+		name := rule.args[0]
+
+		existing := ""
+		for key, value := range headers {
+			if strings.EqualFold(key, name) {
+				existing = value
+				delete(headers, key)
+			}
+		}
+
+		switch rule.action {
+		case actionSet:
+			headers[name] = rule.args[1]
+		case actionAdd:
+			if existing != "" {
+				headers[name] = existing + ", " + rule.args[1]
+			} else {
+				headers[name] = rule.args[1]
+			}
+		}
+	}
+}
+
+// HasParamRules 是否有 URL 参数的改写规则
+func (r *Rules) HasParamRules() bool {
+	return r != nil && len(r.params) > 0
+}
+
+// RewriteParams 改写 URL 参数
+func (r *Rules) RewriteParams(params url.Values) {
+	if r == nil {
+		return
+	}
+
+	for _, rule := range r.params {
+		name := rule.args[0]
+
+		switch rule.action {
+		case actionSet:
+			params.Set(name, rule.args[1])
+		case actionAdd:
+			params.Add(name, rule.args[1])
+		case actionDel:
+			params.Del(name)
+		case actionRename:
+			if values, ok := params[name]; ok {
+				params.Del(name)
+				params[rule.args[1]] = append(params[rule.args[1]], values...)
+			}
+		}
+	}
+}
+
+// HasBodyRules 是否有请求体的改写规则
+func (r *Rules) HasBodyRules() bool {
+	return r != nil && len(r.body) > 0
+}
+
+// RewriteBody 改写反序列化之后的 JSON 请求体，body.move 的路径没有匹配到字段时跳过
+func (r *Rules) RewriteBody(body interface{}) error {
+	if r == nil {
+		return nil
+	}
+
+	for _, rule := range r.body {
+		var err error
+		switch rule.action {
+		case actionSet:
+			err = util.SetJsonPath(body, rule.args[0], rule.value)
+		case actionDel:
+			_, err = util.DeleteJsonPath(body, rule.args[0])
+		case actionRename:
+			_, err = util.RenameJsonPath(body, rule.args[0], rule.args[1])
+		case actionMove:
+			err = moveBodyField(body, rule.args[0], rule.args[1])
+		}
+
+		if err != nil {
+			return errors.New("failed to rewrite body " + rule.action + " " + rule.args[0] + ": " + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// moveBodyField 把 from 匹配到的字段移动到 to
+func moveBodyField(body interface{}, from string, to string) error {
+	nodes, err := util.FindJsonNodes(body, from)
+	if err != nil {
+		return err
+	}
+
+	existingNodes := make([]*util.JsonNode, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Exists() {
+			existingNodes = append(existingNodes, node)
+		}
+	}
+
+	if len(existingNodes) == 0 {
+		return nil
+	}
+
+	if len(existingNodes) > 1 {
+		return errors.New("path matches more than one field")
+	}
+
+	// 只能移动对象中的属性
+	count, err := util.DeleteJsonPath(body, existingNodes[0].Path())
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return errors.New("only object fields can be moved")
+	}
+
+	return util.SetJsonPath(body, to, existingNodes[0].Value())
+}
@@ -0,0 +1,129 @@
+package rewrite
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, rules)
+
+	// nil 表示不改写
+	assert.Equal(t, "/a", rules.RewritePath("/a"))
+	assert.False(t, rules.HasBodyRules())
+	assert.False(t, rules.HasParamRules())
+
+	rules, err = Parse([]string{"header.set:Authorization:Bearer a:b", "body.move:$.user.id:items[0].id"})
+	assert.Nil(t, err)
+	assert.True(t, rules.HasBodyRules())
+	assert.False(t, rules.HasParamRules())
+
+	// 最后一个参数可以包含 :
+	headers := map[string]string{}
+	rules.RewriteHeaders(headers)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer a:b"}, headers)
+
+	invalidRules := []string{
+		"header.unknown:a:b",
+		"header.set:a",
+		"header.del:",
+		"param.rename:a",
+		"path.replace:[:b",
+		"body.del:$.a[",
+		"body.set:a[*]:1",
+		"body.move:a:..b",
+	}
+	for _, invalidRule := range invalidRules {
+		_, err = Parse([]string{invalidRule})
+		assert.NotNil(t, err, invalidRule)
+	}
+}
+
+func TestRewritePath(t *testing.T) {
+	rules, err := Parse([]string{`path.replace:^/api/v1/:/api/v2/`, `path.replace:/users/(\d+)$:/user/$1`})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/api/v2/user/12", rules.RewritePath("/api/v1/users/12"))
+	assert.Equal(t, "/other/v1/", rules.RewritePath("/other/v1/"))
+
+	// 正则表达式中的 : 需要转义，替换内容是最后一个参数，可以直接包含 :
+	rules, err = Parse([]string{`path.replace:^/(?\:v1|v2)/:/v3/`, `path.replace:/(\d{2})\:(\d{2})$:/$1-$2:00`})
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/v3/at/10-30:00", rules.RewritePath("/v2/at/10:30"))
+	assert.Equal(t, "/v4/at/1030", rules.RewritePath("/v4/at/1030"))
+
+	rules, err = Parse([]string{`param.rename:a\:b:c\:d`})
+	assert.Nil(t, err)
+
+	params := url.Values{"a:b": {"1"}}
+	rules.RewriteParams(params)
+	assert.Equal(t, url.Values{"c:d": {"1"}}, params)
+}
+
+func TestRewriteHeaders(t *testing.T) {
+	rules, err := Parse([]string{"header.set:X-Version:2", "header.add:accept:text/plain", "header.del:x-token", "header.add:X-New:1"})
+	assert.Nil(t, err)
+
+	headers := map[string]string{"x-version": "1", "Accept": "application/json", "X-Token": "abc", "Name": "a"}
+	rules.RewriteHeaders(headers)
+
+	assert.Equal(t, map[string]string{"X-Version": "2", "accept": "application/json, text/plain", "Name": "a", "X-New": "1"}, headers)
+}
+
+func TestRewriteParams(t *testing.T) {
+	rules, err := Parse([]string{"param.rename:uid:user_id", "param.set:version:2", "param.add:tag:b", "param.del:debug", "param.rename:notExist:a"})
+	assert.Nil(t, err)
+
+	params := url.Values{"uid": {"1", "2"}, "version": {"1"}, "tag": {"a"}, "debug": {"true"}}
+	rules.RewriteParams(params)
+
+	assert.Equal(t, url.Values{"user_id": {"1", "2"}, "version": {"2"}, "tag": {"a", "b"}}, params)
+}
+
+func TestRewriteBody(t *testing.T) {
+	rules, err := Parse([]string{
+		"body.rename:$..uid:userId",
+		"body.move:$.user.name:$.profile.name",
+		"body.set:$.version:2",
+		"body.set:$.source:migrate",
+		`body.set:$.extra:{"a":[1]}`,
+		"body.del:$.debug",
+		"body.move:$.notExist:$.a",
+	})
+	assert.Nil(t, err)
+
+	body := decode(`{"uid":12345678901234567890,"user":{"name":"a","uid":2},"items":[{"uid":3}],"debug":true}`)
+	assert.Nil(t, rules.RewriteBody(body))
+
+	marshal, err := json.Marshal(body)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"userId":12345678901234567890,"user":{"userId":2},"items":[{"userId":3}],"profile":{"name":"a"},"version":2,"source":"migrate","extra":{"a":[1]}}`, string(marshal))
+	// 大整数不会丢失精度
+	assert.Contains(t, string(marshal), "12345678901234567890")
+
+	rules, err = Parse([]string{"body.move:$.items[*].uid:$.uid"})
+	assert.Nil(t, err)
+	assert.NotNil(t, rules.RewriteBody(decode(`{"items":[{"uid":1},{"uid":2}]}`)))
+
+	rules, err = Parse([]string{"body.move:$.items[0]:$.first"})
+	assert.Nil(t, err)
+	assert.NotNil(t, rules.RewriteBody(decode(`{"items":[1]}`)))
+}
+
+func decode(text string) interface{} {
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		panic(err)
+	}
+
+	return value
+}
@@ -0,0 +1,74 @@
+package variable
+
+import (
+	"errors"
+	"strings"
+)
+
+// 模板中变量的起止标记
+const (
+	delimiterLeft  = "{{"
+	delimiterRight = "}}"
+)
+
+// Template 包含 {{变量名}} 的文本模板，变量名两侧的空白会被忽略
+type Template struct {
+	// texts 变量之间的文本，长度比 names 多 1
+	texts []string
+	// names 模板中按顺序出现的变量名
+	names []string
+}
+
+// IsTemplate 文本中是否包含变量
+func IsTemplate(text string) bool {
+	return strings.Contains(text, delimiterLeft)
+}
+
+// ParseTemplate 解析模板，变量没有结束标记或者变量名为空时返回错误
+func ParseTemplate(text string) (*Template, error) {
+	template := &Template{}
+
+	for {
+		start := strings.Index(text, delimiterLeft)
+		if start < 0 {
+			template.texts = append(template.texts, text)
+			return template, nil
+		}
+
+		end := strings.Index(text[start+len(delimiterLeft):], delimiterRight)
+		if end < 0 {
+			return nil, errors.New("template variable is not closed: " + text[start:])
+		}
+
+		name := strings.TrimSpace(text[start+len(delimiterLeft) : start+len(delimiterLeft)+end])
+		if name == "" {
+			return nil, errors.New("template variable name cannot be empty")
+		}
+
+		template.texts = append(template.texts, text[:start])
+		template.names = append(template.names, name)
+		text = text[start+len(delimiterLeft)+end+len(delimiterRight):]
+	}
+}
+
+// Names 模板中使用的变量名，按出现的顺序排列，可能重复
+func (t *Template) Names() []string {
+	return t.names
+}
+
+// Execute 使用变量的值替换模板中的变量，变量不存在时返回错误
+func (t *Template) Execute(values map[string]string) (string, error) {
+	builder := &strings.Builder{}
+	for index, name := range t.names {
+		value, ok := values[name]
+		if !ok {
+			return "", errors.New("template variable is not defined: " + name)
+		}
+
+		builder.WriteString(t.texts[index])
+		builder.WriteString(value)
+	}
+	builder.WriteString(t.texts[len(t.texts)-1])
+
+	return builder.String(), nil
+}
@@ -0,0 +1,37 @@
+package variable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplate(t *testing.T) {
+	template, err := ParseTemplate(`{"path":"/user/{{ id }}","params":{"ts":"{{ts}}","id":"{{id}}"}}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"id", "ts", "id"}, template.Names())
+
+	result, err := template.Execute(map[string]string{"id": "7", "ts": "100"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"path":"/user/7","params":{"ts":"100","id":"7"}}`, result)
+
+	_, err = template.Execute(map[string]string{"id": "7"})
+	assert.NotNil(t, err)
+
+	// 没有变量的模板原样输出
+	template, err = ParseTemplate("plain")
+	assert.Nil(t, err)
+	assert.Empty(t, template.Names())
+	result, err = template.Execute(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", result)
+
+	_, err = ParseTemplate("/user/{{id")
+	assert.NotNil(t, err)
+
+	_, err = ParseTemplate("/user/{{ }}")
+	assert.NotNil(t, err)
+
+	assert.True(t, IsTemplate("/user/{{id}}"))
+	assert.False(t, IsTemplate("/user/1"))
+}
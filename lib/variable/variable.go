@@ -0,0 +1,357 @@
+package variable
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 变量类型
+const (
+	// TypeRange 整数区间，格式为 名称:range:开始:结束[:步长]，包含开始和结束
+	TypeRange = "range"
+	// TypeCsv CSV 文件的每一行，格式为 名称:csv:文件路径，第一行为列名，通过 名称.列名 引用
+	TypeCsv = "csv"
+	// TypeRandom 随机整数，格式为 名称:random:最小值:最大值，包含最小值和最大值
+	TypeRandom = "random"
+	// TypeUuid 随机 UUID，格式为 名称:uuid
+	TypeUuid = "uuid"
+	// TypeNow 当前时间，格式为 名称:now[:格式]，格式为 unix、unix_milli、unix_nano、rfc3339，默认 unix
+	TypeNow = "now"
+)
+
+// 当前时间的格式
+const (
+	NowUnix      = "unix"
+	NowUnixMilli = "unix_milli"
+	NowUnixNano  = "unix_nano"
+	NowRfc3339   = "rfc3339"
+)
+
+// namePattern 变量名只能包含字母、数字和下划线
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Set 一组变量
+//
+// range 和 csv 为迭代变量，按照定义的顺序组合出所有的取值，后定义的变量变化最快；
+// random、uuid 和 now 为生成变量，每次取值时重新生成
+type Set struct {
+	iterators  []iterator
+	generators []generator
+	// names 可以在模板中引用的变量名
+	names map[string]bool
+	// count 迭代变量所有取值组合的数量
+	count int64
+}
+
+// iterator 迭代变量
+type iterator interface {
+	// size 取值的数量
+	size() int64
+	// fill 把第 index 个取值写入 values
+	fill(index int64, values map[string]string)
+}
+
+// generator 生成变量
+type generator struct {
+	name     string
+	generate func() string
+}
+
+// Parse 解析变量定义，csv 文件的相对路径相对于 workDir
+func Parse(definitions []string, workDir string) (*Set, error) {
+	set := &Set{names: make(map[string]bool)}
+	definedNames := make(map[string]bool)
+
+	for _, definition := range definitions {
+		parts := strings.SplitN(strings.TrimSpace(definition), ":", 3)
+		if len(parts) < 2 {
+			return nil, errors.New("invalid variable format, should be name:type[:args]: " + definition)
+		}
+
+		name := strings.TrimSpace(parts[0])
+		variableType := strings.ToLower(strings.TrimSpace(parts[1]))
+		args := ""
+		if len(parts) == 3 {
+			args = strings.TrimSpace(parts[2])
+		}
+
+		if !namePattern.MatchString(name) {
+			return nil, errors.New("invalid variable name: " + name)
+		}
+
+		if definedNames[name] {
+			return nil, errors.New("variable is duplicated: " + name)
+		}
+		definedNames[name] = true
+
+		var err error
+		switch variableType {
+		case TypeRange:
+			err = set.addRange(name, args)
+		case TypeCsv:
+			err = set.addCsv(name, args, workDir)
+		case TypeRandom:
+			err = set.addRandom(name, args)
+		case TypeUuid:
+			set.addGenerator(name, newUuid)
+		case TypeNow:
+			err = set.addNow(name, args)
+		default:
+			err = errors.New("variable type is not supported: " + variableType)
+		}
+
+		if err != nil {
+			return nil, errors.New("invalid variable " + name + ": " + err.Error())
+		}
+	}
+
+	return set, nil
+}
+
+// HasIterator 是否定义了迭代变量
+func (s *Set) HasIterator() bool {
+	return len(s.iterators) > 0
+}
+
+// HasGenerator 是否定义了生成变量
+func (s *Set) HasGenerator() bool {
+	return len(s.generators) > 0
+}
+
+// Count 迭代变量所有取值组合的数量，没有迭代变量时为 0
+func (s *Set) Count() int64 {
+	return s.count
+}
+
+// Has 是否定义了变量
+func (s *Set) Has(name string) bool {
+	return s.names[name]
+}
+
+// Check 检查模板中的变量是否都已经定义
+func (s *Set) Check(template *Template) error {
+	for _, name := range template.Names() {
+		if !s.Has(name) {
+			return errors.New("template variable is not defined: " + name)
+		}
+	}
+
+	return nil
+}
+
+// Iterate 迭代变量第 index 个取值组合，index 从 0 开始
+func (s *Set) Iterate(index int64) map[string]string {
+	values := make(map[string]string, len(s.names))
+
+	// 后定义的变量变化最快
+	for i := len(s.iterators) - 1; i >= 0; i-- {
+		size := s.iterators[i].size()
+		s.iterators[i].fill(index%size, values)
+		index /= size
+	}
+
+	return values
+}
+
+// Generate 生成变量的值，每次调用都重新生成
+func (s *Set) Generate() map[string]string {
+	values := make(map[string]string, len(s.generators))
+	for _, generator := range s.generators {
+		values[generator.name] = generator.generate()
+	}
+
+	return values
+}
+
+func (s *Set) addIterator(it iterator, names ...string) error {
+	size := it.size()
+	if size <= 0 {
+		return errors.New("variable has no value")
+	}
+
+	if s.count == 0 {
+		s.count = size
+	} else if s.count > math.MaxInt64/size {
+		return errors.New("too many combinations of variables")
+	} else {
+		s.count *= size
+	}
+
+	s.iterators = append(s.iterators, it)
+	for _, name := range names {
+		s.names[name] = true
+	}
+
+	return nil
+}
+
+func (s *Set) addGenerator(name string, generate func() string) {
+	s.generators = append(s.generators, generator{name: name, generate: generate})
+	s.names[name] = true
+}
+
+// rangeIterator 整数区间
+type rangeIterator struct {
+	name  string
+	start int64
+	step  int64
+	count int64
+}
+
+func (s *Set) addRange(name string, args string) error {
+	parts := strings.Split(args, ":")
+	if len(parts) != 2 && len(parts) != 3 {
+		return errors.New("range should be start:end[:step]")
+	}
+
+	numbers := make([]int64, 0, 3)
+	for _, part := range parts {
+		number, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return errors.New("range should be integers: " + args)
+		}
+		numbers = append(numbers, number)
+	}
+
+	start, end, step := numbers[0], numbers[1], int64(1)
+	if len(numbers) == 3 {
+		step = numbers[2]
+	}
+
+	if step == 0 || (end > start && step < 0) || (end < start && step > 0) {
+		return errors.New("range step cannot reach the end: " + args)
+	}
+
+	return s.addIterator(&rangeIterator{name: name, start: start, step: step, count: (end-start)/step + 1}, name)
+}
+
+func (r *rangeIterator) size() int64 {
+	return r.count
+}
+
+func (r *rangeIterator) fill(index int64, values map[string]string) {
+	values[r.name] = strconv.FormatInt(r.start+index*r.step, 10)
+}
+
+// csvIterator CSV 文件的每一行，列名为 变量名.列名
+type csvIterator struct {
+	names []string
+	rows  [][]string
+}
+
+func (s *Set) addCsv(name string, filePath string, workDir string) error {
+	if filePath == "" {
+		return errors.New("csv file cannot be empty")
+	}
+
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(workDir, filePath)
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rows, err := readCsv(file)
+	if err != nil {
+		return errors.New("failed to read csv file " + filePath + ": " + err.Error())
+	}
+
+	if len(rows) == 0 {
+		return errors.New("csv file is empty: " + filePath)
+	}
+
+	it := &csvIterator{rows: rows[1:]}
+	for _, column := range rows[0] {
+		it.names = append(it.names, name+"."+strings.TrimSpace(column))
+	}
+
+	return s.addIterator(it, it.names...)
+}
+
+// readCsv 读取 CSV 文件的所有行，每行的列数需要和第一行一致
+func readCsv(reader io.Reader) ([][]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	return csvReader.ReadAll()
+}
+
+func (c *csvIterator) size() int64 {
+	return int64(len(c.rows))
+}
+
+func (c *csvIterator) fill(index int64, values map[string]string) {
+	for column, name := range c.names {
+		values[name] = c.rows[index][column]
+	}
+}
+
+func (s *Set) addRandom(name string, args string) error {
+	parts := strings.Split(args, ":")
+	if len(parts) != 2 {
+		return errors.New("random should be min:max")
+	}
+
+	minValue, errMin := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	maxValue, errMax := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if errMin != nil || errMax != nil {
+		return errors.New("random should be integers: " + args)
+	}
+
+	if minValue > maxValue || maxValue-minValue < 0 || maxValue-minValue == math.MaxInt64 {
+		return errors.New("random range is invalid: " + args)
+	}
+
+	s.addGenerator(name, func() string {
+		return strconv.FormatInt(minValue+mathrand.Int63n(maxValue-minValue+1), 10)
+	})
+
+	return nil
+}
+
+func (s *Set) addNow(name string, format string) error {
+	var generate func() string
+	switch strings.ToLower(format) {
+	case "", NowUnix:
+		generate = func() string { return strconv.FormatInt(time.Now().Unix(), 10) }
+	case NowUnixMilli:
+		generate = func() string { return strconv.FormatInt(time.Now().UnixMilli(), 10) }
+	case NowUnixNano:
+		generate = func() string { return strconv.FormatInt(time.Now().UnixNano(), 10) }
+	case NowRfc3339:
+		generate = func() string { return time.Now().Format(time.RFC3339) }
+	default:
+		return errors.New("now format is not supported: " + format)
+	}
+
+	s.addGenerator(name, generate)
+
+	return nil
+}
+
+// newUuid 生成随机的 UUID v4
+func newUuid() string {
+	buffer := make([]byte, 16)
+	_, _ = rand.Read(buffer)
+
+	buffer[6] = (buffer[6] & 0x0f) | 0x40
+	buffer[8] = (buffer[8] & 0x3f) | 0x80
+
+	encoded := hex.EncodeToString(buffer)
+
+	return encoded[0:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:]
+}
@@ -0,0 +1,116 @@
+package variable
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	set, err := Parse([]string{"id:range:1:5", "page:range:10:0:-5"}, "")
+	assert.Nil(t, err)
+	assert.True(t, set.HasIterator())
+	assert.False(t, set.HasGenerator())
+	assert.Equal(t, int64(15), set.Count())
+
+	// 后定义的变量变化最快
+	assert.Equal(t, map[string]string{"id": "1", "page": "10"}, set.Iterate(0))
+	assert.Equal(t, map[string]string{"id": "1", "page": "0"}, set.Iterate(2))
+	assert.Equal(t, map[string]string{"id": "2", "page": "10"}, set.Iterate(3))
+	assert.Equal(t, map[string]string{"id": "5", "page": "0"}, set.Iterate(14))
+
+	_, err = Parse([]string{"id:range:1:5:-1"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"id:range:1:5:0"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"id:range:a:5"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"id:range:1"}, "")
+	assert.NotNil(t, err)
+}
+
+func TestParseCsv(t *testing.T) {
+	workDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(workDir, "users.csv"), []byte("id,name\n1,\"a,b\"\n2,c\n"), 0644))
+
+	set, err := Parse([]string{"user:csv:users.csv", "n:range:1:2"}, workDir)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), set.Count())
+	assert.True(t, set.Has("user.id"))
+	assert.True(t, set.Has("user.name"))
+	assert.False(t, set.Has("user"))
+
+	assert.Equal(t, map[string]string{"user.id": "1", "user.name": "a,b", "n": "2"}, set.Iterate(1))
+	assert.Equal(t, map[string]string{"user.id": "2", "user.name": "c", "n": "1"}, set.Iterate(2))
+
+	// 只有列名没有数据
+	assert.Nil(t, os.WriteFile(filepath.Join(workDir, "empty.csv"), []byte("id,name\n"), 0644))
+	_, err = Parse([]string{"user:csv:empty.csv"}, workDir)
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"user:csv:not_exist.csv"}, workDir)
+	assert.NotNil(t, err)
+}
+
+func TestParseGenerator(t *testing.T) {
+	set, err := Parse([]string{"r:random:1:3", "u:uuid", "ts:now", "ms:now:unix_milli", "date:now:rfc3339"}, "")
+	assert.Nil(t, err)
+	assert.False(t, set.HasIterator())
+	assert.True(t, set.HasGenerator())
+	assert.Equal(t, int64(0), set.Count())
+
+	for i := 0; i < 100; i++ {
+		values := set.Generate()
+
+		number, err := strconv.Atoi(values["r"])
+		assert.Nil(t, err)
+		assert.True(t, number >= 1 && number <= 3)
+
+		assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), values["u"])
+		assert.Len(t, values["ts"], 10)
+		assert.Len(t, values["ms"], 13)
+		assert.NotEmpty(t, values["date"])
+	}
+
+	assert.NotEqual(t, set.Generate()["u"], set.Generate()["u"])
+
+	_, err = Parse([]string{"r:random:3:1"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"ts:now:yyyy"}, "")
+	assert.NotNil(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]string{"id"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"a-b:uuid"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"id:uuid", "id:now"}, "")
+	assert.NotNil(t, err)
+
+	_, err = Parse([]string{"id:unknown"}, "")
+	assert.NotNil(t, err)
+}
+
+func TestSetCheck(t *testing.T) {
+	set, err := Parse([]string{"id:range:1:2", "ts:now"}, "")
+	assert.Nil(t, err)
+
+	template, err := ParseTemplate("{{id}}-{{ts}}")
+	assert.Nil(t, err)
+	assert.Nil(t, set.Check(template))
+
+	template, err = ParseTemplate("{{id}}-{{name}}")
+	assert.Nil(t, err)
+	assert.NotNil(t, set.Check(template))
+}
@@ -0,0 +1,151 @@
+package util
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// 支持的压缩格式
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// CompressionExtension 压缩格式对应的文件扩展名，不压缩时返回空字符串
+func CompressionExtension(compression string) string {
+	switch compression {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// FileCompression 根据文件扩展名判断压缩格式，不是压缩文件时返回空字符串
+func FileCompression(filePath string) string {
+	switch {
+	case strings.HasSuffix(filePath, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(filePath, ".zst"):
+		return CompressionZstd
+	default:
+		return ""
+	}
+}
+
+// compressedReader 关闭时同时关闭解压器和文件
+type compressedReader struct {
+	io.Reader
+	closeFunc func()
+	file      *os.File
+}
+
+func (r *compressedReader) Close() error {
+	r.closeFunc()
+	return r.file.Close()
+}
+
+// OpenReader 打开文件，.gz 和 .zst 结尾的文件读取时自动解压
+func OpenReader(filePath string) (io.ReadCloser, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch FileCompression(filePath) {
+	case CompressionGzip:
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &compressedReader{Reader: reader, closeFunc: func() { _ = reader.Close() }, file: file}, nil
+	case CompressionZstd:
+		reader, err := zstd.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &compressedReader{Reader: reader, closeFunc: reader.Close, file: file}, nil
+	default:
+		return file, nil
+	}
+}
+
+// compressor gzip 和 zstd 的压缩器
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// Flusher 可以把缓冲的数据写入文件的 Writer
+type Flusher interface {
+	Flush() error
+}
+
+// compressedWriter 关闭时先关闭压缩器写入剩余的数据，再同步和关闭文件
+type compressedWriter struct {
+	io.Writer
+	compressor compressor
+	file       *os.File
+}
+
+// Flush 把压缩器中缓冲的数据写入文件，没有压缩时直接写文件，不需要刷新
+func (w *compressedWriter) Flush() error {
+	if w.compressor == nil {
+		return nil
+	}
+
+	return w.compressor.Flush()
+}
+
+func (w *compressedWriter) Close() error {
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			_ = w.file.Close()
+			return err
+		}
+	}
+
+	if err := w.file.Sync(); err != nil {
+		_ = w.file.Close()
+		return err
+	}
+
+	return w.file.Close()
+}
+
+// OpenWriter 按 flag 打开文件，.gz 和 .zst 结尾的文件写入时自动压缩
+//
+// 追加写入压缩文件时会在文件末尾添加新的压缩流，gzip 和 zstd 都支持读取多个连续的压缩流
+func OpenWriter(filePath string, flag int) (io.WriteCloser, error) {
+	file, err := os.OpenFile(filePath, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &compressedWriter{Writer: file, file: file}
+	switch FileCompression(filePath) {
+	case CompressionGzip:
+		writer.compressor = gzip.NewWriter(file)
+	case CompressionZstd:
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		writer.compressor = encoder
+	}
+
+	if writer.compressor != nil {
+		writer.Writer = writer.compressor
+	}
+
+	return writer, nil
+}
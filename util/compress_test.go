@@ -0,0 +1,75 @@
+package util
+
+import (
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCompression(t *testing.T) {
+	assert.Equal(t, CompressionGzip, FileCompression("payload.jsonl.gz"))
+	assert.Equal(t, CompressionZstd, FileCompression("payload.jsonl.zst"))
+	assert.Equal(t, "", FileCompression("payload.jsonl"))
+
+	assert.Equal(t, ".gz", CompressionExtension(CompressionGzip))
+	assert.Equal(t, ".zst", CompressionExtension(CompressionZstd))
+	assert.Equal(t, "", CompressionExtension(""))
+}
+
+func TestCompressedReadWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"file.txt", "file.txt.gz", "file.txt.zst"} {
+		filePath := path.Join(dir, name)
+
+		writer, err := OpenWriter(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+		assert.Nil(t, err)
+		_, err = io.WriteString(writer, "line1\nline2\n")
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+
+		// 追加写入时压缩文件中有两个连续的压缩流
+		writer, err = OpenWriter(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND)
+		assert.Nil(t, err)
+		_, err = io.WriteString(writer, "line3\n")
+		assert.Nil(t, err)
+		assert.Nil(t, writer.Close())
+
+		reader, err := OpenReader(filePath)
+		assert.Nil(t, err)
+		content, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Nil(t, reader.Close())
+		assert.Equal(t, "line1\nline2\nline3\n", string(content), name)
+
+		count, err := FileLineCount(filePath)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, count, name)
+	}
+}
+
+func TestCompressedWriterFlush(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"file.txt", "file.txt.gz", "file.txt.zst"} {
+		filePath := path.Join(dir, name)
+
+		writer, err := OpenWriter(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC)
+		assert.Nil(t, err)
+		_, err = io.WriteString(writer, "line1\n")
+		assert.Nil(t, err)
+		assert.Nil(t, writer.(Flusher).Flush())
+
+		// 刷新之后没有关闭的压缩流中也能读到已经写入的数据
+		reader, err := OpenReader(filePath)
+		assert.Nil(t, err)
+		content, _ := io.ReadAll(reader)
+		assert.Nil(t, reader.Close())
+		assert.Equal(t, "line1\n", string(content), name)
+
+		assert.Nil(t, writer.Close())
+	}
+}
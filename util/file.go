@@ -5,11 +5,13 @@ import (
 	"os"
 )
 
+// FileLineCount 统计文件的行数，压缩文件统计解压后的行数
 func FileLineCount(fileName string) (int, error) {
-	f, err := os.Open(fileName)
+	f, err := OpenReader(fileName)
 	if err != nil {
 		return 0, err
 	}
+	defer f.Close()
 
 	maxReadSize := 1024 * 1024 // 1MB buffer size
 	buffer := make([]byte, 0, maxReadSize)
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// histogramGrowth 相邻两个桶的比例，分位数的相对误差不超过 5%
+	histogramGrowth = 1.05
+	// histogramBuckets 桶的数量，最大可以记录约 100s 的耗时，超过的记录在最后一个桶中
+	histogramBuckets = 400
+)
+
+// Histogram 耗时分布，使用指数增长的桶记录耗时，内存占用固定，可以并发使用
+type Histogram struct {
+	lock   sync.Mutex
+	counts []int64
+	count  int64
+	max    time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make([]int64, histogramBuckets),
+	}
+}
+
+// Record 记录一次耗时
+func (h *Histogram) Record(duration time.Duration) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.counts[histogramBucket(duration)]++
+	h.count++
+	if duration > h.max {
+		h.max = duration
+	}
+}
+
+// Count 记录的次数
+func (h *Histogram) Count() int64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.count
+}
+
+// Max 最大耗时
+func (h *Histogram) Max() time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.max
+}
+
+// Quantile 分位数耗时，quantile 取值范围为 0 到 1，返回对应桶的上限，不超过最大耗时
+func (h *Histogram) Quantile(quantile float64) time.Duration {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if h.count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(quantile * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for index, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			upper := histogramUpperBound(index)
+			if upper > h.max {
+				return h.max
+			}
+			return upper
+		}
+	}
+
+	return h.max
+}
+
+// histogramBucket 耗时所在的桶，第 i 个桶记录 [growth^(i-1), growth^i) 微秒的耗时，第 0 个桶记录小于 1 微秒的耗时
+func histogramBucket(duration time.Duration) int {
+	microseconds := float64(duration) / float64(time.Microsecond)
+	if microseconds < 1 {
+		return 0
+	}
+
+	index := int(math.Log(microseconds)/math.Log(histogramGrowth)) + 1
+	if index >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+
+	return index
+}
+
+// histogramUpperBound 桶的上限
+func histogramUpperBound(index int) time.Duration {
+	return time.Duration(math.Pow(histogramGrowth, float64(index)) * float64(time.Microsecond))
+}
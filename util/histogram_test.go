@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	histogram := NewHistogram()
+	assert.Equal(t, time.Duration(0), histogram.Quantile(0.5))
+
+	for i := 1; i <= 100; i++ {
+		histogram.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, int64(100), histogram.Count())
+	assert.Equal(t, 100*time.Millisecond, histogram.Max())
+	assert.InEpsilon(t, float64(50*time.Millisecond), float64(histogram.Quantile(0.5)), 0.05)
+	assert.InEpsilon(t, float64(90*time.Millisecond), float64(histogram.Quantile(0.9)), 0.05)
+	assert.InEpsilon(t, float64(99*time.Millisecond), float64(histogram.Quantile(0.99)), 0.05)
+	assert.Equal(t, 100*time.Millisecond, histogram.Quantile(1))
+}
+
+func TestHistogramBucket(t *testing.T) {
+	assert.Equal(t, 0, histogramBucket(0))
+	assert.Equal(t, 0, histogramBucket(500*time.Nanosecond))
+	assert.Equal(t, histogramBuckets-1, histogramBucket(time.Hour))
+
+	for _, duration := range []time.Duration{time.Microsecond, 3 * time.Millisecond, 2 * time.Second} {
+		assert.LessOrEqual(t, duration, histogramUpperBound(histogramBucket(duration)))
+	}
+}
@@ -0,0 +1,321 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// AllArrays 匹配所有数组的路径
+const AllArrays = "*"
+
+// UnorderedArrayRule 无序数组规则
+type UnorderedArrayRule struct {
+	// Path 数组路径，语法见 SetJsonPathToNil，AllArrays 表示所有数组
+	Path string
+	// Key 数组元素的 key 字段，不为空时按 key 匹配元素，为空时按元素内容匹配
+	Key string
+}
+
+// ArrayDiff 无序数组的对比结果
+type ArrayDiff struct {
+	// Path 数组路径
+	Path string `json:"path"`
+	// Added 只在 B 中存在的元素，按 key 匹配时为 key=value 的形式，否则为元素的 JSON
+	Added []string `json:"added,omitempty"`
+	// Removed 只在 A 中存在的元素
+	Removed []string `json:"removed,omitempty"`
+	// Changed key 相同但内容不同的元素，只在按 key 匹配时存在
+	Changed []string `json:"changed,omitempty"`
+}
+
+// UnorderedArrays 规范化之后的无序数组，用于对比之后恢复原来的数组
+type UnorderedArrays struct {
+	nodes  []*JsonNode
+	arrays map[string]*unorderedArray
+	paths  []string
+}
+
+// keyedArray 按 key 匹配的数组，属性名为 key=value 的形式
+type keyedArray map[string]interface{}
+
+var keyedArrayType = reflect.TypeOf(keyedArray{})
+
+type unorderedArray struct {
+	// keyed 是否按 key 匹配，按 key 匹配时 value 为 map，否则为排序后的数组
+	keyed bool
+	value interface{}
+}
+
+// NormalizeUnorderedArrays 把规则匹配到的数组转换为和顺序无关的形式
+//
+// 指定 key 时数组被转换为以 key=value 为属性名的 map，否则按元素的 JSON 排序。
+// 嵌套的数组从内层开始处理，根节点是数组时不做处理
+func NormalizeUnorderedArrays(jsonData interface{}, rules []UnorderedArrayRule) (*UnorderedArrays, error) {
+	result := &UnorderedArrays{arrays: make(map[string]*unorderedArray)}
+
+	type ruleDepth struct {
+		rule  UnorderedArrayRule
+		depth int
+	}
+
+	sortedRules := make([]ruleDepth, 0, len(rules))
+	for _, rule := range rules {
+		depth := 0
+		if rule.Path != AllArrays {
+			segments, err := parseJsonPath(rule.Path)
+			if err != nil {
+				return nil, err
+			}
+			depth = len(segments)
+		}
+		sortedRules = append(sortedRules, ruleDepth{rule: rule, depth: depth})
+	}
+
+	// 路径越深越先处理，所有数组的规则最后处理
+	sort.SliceStable(sortedRules, func(i, j int) bool {
+		return sortedRules[i].depth > sortedRules[j].depth
+	})
+
+	for _, item := range sortedRules {
+		var nodes []*JsonNode
+		if item.rule.Path == AllArrays {
+			nodes = arrayNodes(jsonData)
+		} else {
+			found, err := FindJsonNodes(jsonData, item.rule.Path)
+			if err != nil {
+				return nil, err
+			}
+			nodes = found
+		}
+
+		for _, node := range nodes {
+			array, ok := node.value.([]interface{})
+			if !ok || !node.exists {
+				continue
+			}
+
+			if _, exists := result.arrays[node.path]; exists {
+				continue
+			}
+
+			normalized := normalizeArray(array, item.rule.Key)
+			node.Set(normalized.value)
+			result.nodes = append(result.nodes, node)
+			result.arrays[node.path] = normalized
+			result.paths = append(result.paths, node.path)
+		}
+	}
+
+	return result, nil
+}
+
+// Restore 恢复原来的数组
+func (u *UnorderedArrays) Restore() {
+	RestoreJsonNodes(u.nodes)
+}
+
+// DiffUnorderedArrays 对比两边相同路径的无序数组，分别返回新增、删除和修改的元素
+//
+// opts 用于判断 key 相同的元素内容是否一致
+func DiffUnorderedArrays(a, b *UnorderedArrays, opts ...cmp.Option) []ArrayDiff {
+	result := make([]ArrayDiff, 0)
+
+	for _, path := range a.paths {
+		// 外层数组无序时内层数组的下标没有意义，差异体现在外层数组的对比结果中
+		if a.nestedInArray(path) {
+			continue
+		}
+
+		arrayA := a.arrays[path]
+		arrayB, ok := b.arrays[path]
+		if !ok || arrayA.keyed != arrayB.keyed {
+			continue
+		}
+
+		var diff ArrayDiff
+		if arrayA.keyed {
+			diff = diffKeyedArray(arrayA.value.(keyedArray), arrayB.value.(keyedArray), opts...)
+		} else {
+			diff = diffSortedArray(arrayA.value.([]interface{}), arrayB.value.([]interface{}))
+		}
+
+		if len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0 {
+			continue
+		}
+
+		diff.Path = path
+		result = append(result, diff)
+	}
+
+	return result
+}
+
+// nestedInArray 判断路径是否位于其他无序数组的元素中
+func (u *UnorderedArrays) nestedInArray(path string) bool {
+	for _, parent := range u.paths {
+		if strings.HasPrefix(path, parent+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// arrayNodes 按从内到外的顺序返回所有数组节点，不包含根节点
+func arrayNodes(jsonData interface{}) []*JsonNode {
+	result := make([]*JsonNode, 0)
+
+	var walk func(value interface{}, path string)
+	walk = func(value interface{}, path string) {
+		switch container := value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(container))
+			for key := range container {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				childPath := ChildKeyPath(path, key)
+				walk(container[key], childPath)
+				if _, ok := container[key].([]interface{}); ok {
+					result = append(result, &JsonNode{container: container, key: key, value: container[key], exists: true, path: childPath})
+				}
+			}
+		case []interface{}:
+			for index, element := range container {
+				childPath := ChildIndexPath(path, index)
+				walk(element, childPath)
+				if _, ok := element.([]interface{}); ok {
+					result = append(result, &JsonNode{container: container, index: index, value: element, exists: true, path: childPath})
+				}
+			}
+		}
+	}
+
+	walk(jsonData, "$")
+
+	return result
+}
+
+// normalizeArray 规范化数组，所有元素都包含 key 字段时转换为 map，否则按元素的 JSON 排序
+func normalizeArray(array []interface{}, key string) *unorderedArray {
+	if key != "" {
+		keyed := make(keyedArray, len(array))
+		ok := true
+		for _, element := range array {
+			m, isMap := element.(map[string]interface{})
+			if !isMap {
+				ok = false
+				break
+			}
+
+			keyValue, exists := m[key]
+			if !exists {
+				ok = false
+				break
+			}
+
+			// key 重复时追加序号
+			name := key + "=" + fmt.Sprint(keyValue)
+			for i := 2; ; i++ {
+				if _, duplicated := keyed[name]; !duplicated {
+					break
+				}
+				name = key + "=" + fmt.Sprint(keyValue) + "#" + strconv.Itoa(i)
+			}
+
+			keyed[name] = element
+		}
+
+		if ok {
+			return &unorderedArray{keyed: true, value: keyed}
+		}
+	}
+
+	sorted := make([]interface{}, len(array))
+	copy(sorted, array)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return canonicalJson(sorted[i]) < canonicalJson(sorted[j])
+	})
+
+	return &unorderedArray{keyed: false, value: sorted}
+}
+
+func diffKeyedArray(a, b keyedArray, opts ...cmp.Option) ArrayDiff {
+	diff := ArrayDiff{}
+
+	for key, valueA := range a {
+		valueB, ok := b[key]
+		if !ok {
+			diff.Removed = append(diff.Removed, key)
+			continue
+		}
+
+		if !cmp.Equal(valueA, valueB, opts...) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+func diffSortedArray(a, b []interface{}) ArrayDiff {
+	diff := ArrayDiff{}
+
+	countA := make(map[string]int)
+	for _, element := range a {
+		countA[canonicalJson(element)]++
+	}
+
+	countB := make(map[string]int)
+	for _, element := range b {
+		countB[canonicalJson(element)]++
+	}
+
+	for _, element := range a {
+		key := canonicalJson(element)
+		if countB[key] > 0 {
+			countB[key]--
+			continue
+		}
+		diff.Removed = append(diff.Removed, key)
+	}
+
+	for _, element := range b {
+		key := canonicalJson(element)
+		if countA[key] > 0 {
+			countA[key]--
+			continue
+		}
+		diff.Added = append(diff.Added, key)
+	}
+
+	return diff
+}
+
+// canonicalJson 把数据转换为 map 属性有序的 JSON 字符串
+func canonicalJson(value interface{}) string {
+	marshal, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(marshal)
+}
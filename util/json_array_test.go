@@ -0,0 +1,96 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeUnorderedArraysWithKey(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	err := json.Unmarshal([]byte(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}]}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"items": [{"id": 4, "name": "d"}, {"id": 2, "name": "x"}, {"id": 1, "name": "a"}]}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	rules := []UnorderedArrayRule{{Path: "items", Key: "id"}}
+
+	arrays1, err := NormalizeUnorderedArrays(data1, rules)
+	assert.Nil(t, err)
+
+	arrays2, err := NormalizeUnorderedArrays(data2, rules)
+	assert.Nil(t, err)
+
+	diffs := DiffUnorderedArrays(arrays1, arrays2)
+	assert.Equal(t, []ArrayDiff{{Path: "$.items", Added: []string{"id=4"}, Removed: []string{"id=3"}, Changed: []string{"id=2"}}}, diffs)
+
+	arrays1.Restore()
+	arrays2.Restore()
+
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}, {"id": 3, "name": "c"}]}`), &expected)
+	assert.Equal(t, "", cmp.Diff(expected, data1))
+}
+
+func TestNormalizeUnorderedArraysAll(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	err := json.Unmarshal([]byte(`{"tags": ["a", "b"], "list": [{"ids": [1, 2]}, {"ids": [3]}]}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"tags": ["b", "a"], "list": [{"ids": [3]}, {"ids": [2, 1]}]}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.NotEqual(t, "", cmp.Diff(data1, data2))
+
+	rules := []UnorderedArrayRule{{Path: AllArrays}}
+
+	arrays1, err := NormalizeUnorderedArrays(data1, rules)
+	assert.Nil(t, err)
+
+	arrays2, err := NormalizeUnorderedArrays(data2, rules)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "", cmp.Diff(data1, data2))
+	assert.Equal(t, 0, len(DiffUnorderedArrays(arrays1, arrays2)))
+}
+
+func TestDiffUnorderedArraysWithoutKey(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	err := json.Unmarshal([]byte(`{"tags": ["a", "b", "b"]}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"tags": ["c", "b", "a"]}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	rules := []UnorderedArrayRule{{Path: "tags"}}
+
+	arrays1, err := NormalizeUnorderedArrays(data1, rules)
+	assert.Nil(t, err)
+
+	arrays2, err := NormalizeUnorderedArrays(data2, rules)
+	assert.Nil(t, err)
+
+	diffs := DiffUnorderedArrays(arrays1, arrays2)
+	assert.Equal(t, []ArrayDiff{{Path: "$.tags", Added: []string{`"c"`}, Removed: []string{`"b"`}}}, diffs)
+}
@@ -0,0 +1,220 @@
+package util
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// NumericTolerance 数字容差，差值小于等于绝对容差或者相对容差乘以两个数中绝对值较大的一个时认为相等
+type NumericTolerance struct {
+	// Path 生效的路径，语法见 SetJsonPathToNil，为空时对所有路径生效
+	Path string
+	// Absolute 绝对容差
+	Absolute float64
+	// Relative 相对容差
+	Relative float64
+}
+
+// CompareOptions JSON 数据对比选项
+type CompareOptions struct {
+	// Tolerance 全局的数字容差
+	Tolerance NumericTolerance
+	// PathTolerances 指定路径的数字容差，优先于全局容差，多个路径匹配时第一个生效
+	PathTolerances []NumericTolerance
+	// StringNumberEqual 字符串和数字表示相同的值时是否认为相等，例如 "123" 和 123
+	StringNumberEqual bool
+}
+
+// NewCmpOptions 根据对比选项创建 cmp 的选项
+func NewCmpOptions(options CompareOptions) ([]cmp.Option, error) {
+	matchers := make([]func(cmp.Path) bool, 0, len(options.PathTolerances))
+	for _, tolerance := range options.PathTolerances {
+		matcher, err := NewCmpPathMatcher(tolerance.Path)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+
+	result := make([]cmp.Option, 0, len(options.PathTolerances)+1)
+
+	// 每个值只能有一个 Comparer 生效，所以按顺序判断第一个匹配的路径
+	for i, tolerance := range options.PathTolerances {
+		index := i
+		filter := func(path cmp.Path) bool {
+			for j := 0; j < index; j++ {
+				if matchers[j](path) {
+					return false
+				}
+			}
+			return matchers[index](path)
+		}
+		result = append(result, cmp.FilterPath(filter, numericComparer(tolerance, options.StringNumberEqual)))
+	}
+
+	if options.Tolerance.Absolute > 0 || options.Tolerance.Relative > 0 || options.StringNumberEqual {
+		filter := func(path cmp.Path) bool {
+			for _, matcher := range matchers {
+				if matcher(path) {
+					return false
+				}
+			}
+			return true
+		}
+		result = append(result, cmp.FilterPath(filter, numericComparer(options.Tolerance, options.StringNumberEqual)))
+	}
+
+	return result, nil
+}
+
+// NewCmpPathMatcher 创建判断 cmp.Path 是否匹配路径表达式的函数，负数下标不会匹配任何路径
+func NewCmpPathMatcher(expression string) (func(cmp.Path) bool, error) {
+	segments, err := parseJsonPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path cmp.Path) bool {
+		return matchSegments(segments, cmpPathSteps(path))
+	}, nil
+}
+
+// CmpPathToJsonPath 把 cmp.Path 转换为 JSON 路径，例如 $.data.items[0].id
+//
+// 按 key 匹配的无序数组中的元素使用 [key=value] 的形式，例如 $.data.items[id=1].name
+func CmpPathToJsonPath(path cmp.Path) string {
+	result := "$"
+	for _, step := range cmpPathSteps(path) {
+		if step.isIndex {
+			result = ChildIndexPath(result, step.index)
+		} else if step.keyed {
+			result = result + "[" + step.key + "]"
+		} else {
+			result = ChildKeyPath(result, step.key)
+		}
+	}
+
+	return result
+}
+
+// jsonPathStep 具体路径中的一级
+type jsonPathStep struct {
+	isIndex bool
+	// keyed 是否为按 key 匹配的无序数组中的元素
+	keyed bool
+	key   string
+	index int
+}
+
+func cmpPathSteps(path cmp.Path) []jsonPathStep {
+	steps := make([]jsonPathStep, 0, len(path))
+	for i, step := range path {
+		switch s := step.(type) {
+		case cmp.MapIndex:
+			keyed := i > 0 && path[i-1].Type() == keyedArrayType
+			steps = append(steps, jsonPathStep{keyed: keyed, key: s.Key().String()})
+		case cmp.SliceIndex:
+			// 两边数组长度不同时只有一边有下标
+			index := s.Key()
+			if index < 0 {
+				indexA, indexB := s.SplitKeys()
+				index = max(indexA, indexB)
+			}
+			steps = append(steps, jsonPathStep{isIndex: true, index: index})
+		}
+	}
+
+	return steps
+}
+
+func matchSegments(segments []jsonPathSegment, steps []jsonPathStep) bool {
+	if len(segments) == 0 {
+		return len(steps) == 0
+	}
+
+	segment := segments[0]
+	if segment.recursive {
+		for i := range steps {
+			if segment.matchStep(steps[i]) && matchSegments(segments[1:], steps[i+1:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(steps) == 0 || !segment.matchStep(steps[0]) {
+		return false
+	}
+
+	return matchSegments(segments[1:], steps[1:])
+}
+
+func (s jsonPathSegment) matchStep(step jsonPathStep) bool {
+	switch {
+	case s.wildcard:
+		return true
+	case s.isIndex:
+		return step.isIndex && step.index == s.index
+	default:
+		return !step.isIndex && !step.keyed && step.key == s.key
+	}
+}
+
+// numericComparer 按容差对比数字，开启 stringNumberEqual 时可以对比字符串形式的数字
+func numericComparer(tolerance NumericTolerance, stringNumberEqual bool) cmp.Option {
+	filter := func(a, b interface{}) bool {
+		_, aIsNumber := a.(float64)
+		_, bIsNumber := b.(float64)
+		if aIsNumber && bIsNumber {
+			return true
+		}
+
+		if !stringNumberEqual || (!aIsNumber && !bIsNumber) {
+			return false
+		}
+
+		_, aOk := toFloat(a)
+		_, bOk := toFloat(b)
+		return aOk && bOk
+	}
+
+	comparer := func(a, b interface{}) bool {
+		x, _ := toFloat(a)
+		y, _ := toFloat(b)
+		return FloatEqual(x, y, tolerance.Absolute, tolerance.Relative)
+	}
+
+	return cmp.FilterValues(filter, cmp.Comparer(comparer))
+}
+
+// FloatEqual 判断两个数在容差范围内是否相等
+func FloatEqual(a, b, absolute, relative float64) bool {
+	if a == b {
+		return true
+	}
+
+	delta := math.Abs(a - b)
+	if delta <= absolute {
+		return true
+	}
+
+	return delta <= relative*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCmpOptionsTolerance(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+	var data3 interface{}
+
+	err := json.Unmarshal([]byte(`{"price": 1.0000001, "score": 10.0, "items": [{"rate": 0.5}]}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"price": 1.0000002, "score": 10.0, "items": [{"rate": 0.5}]}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"price": 1.0000002, "score": 10.5, "items": [{"rate": 0.51}]}`), &data3)
+	if err != nil {
+		panic(err)
+	}
+
+	options, err := NewCmpOptions(CompareOptions{
+		Tolerance: NumericTolerance{Absolute: 0.000001},
+	})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", cmp.Diff(data1, data2))
+	assert.Equal(t, "", cmp.Diff(data1, data2, options...))
+	assert.NotEqual(t, "", cmp.Diff(data1, data3, options...))
+
+	options, err = NewCmpOptions(CompareOptions{
+		Tolerance: NumericTolerance{Absolute: 0.000001},
+		PathTolerances: []NumericTolerance{
+			{Path: "score", Relative: 0.1},
+			{Path: "items[*].rate", Absolute: 0.1},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cmp.Diff(data1, data3, options...))
+
+	_, err = NewCmpOptions(CompareOptions{PathTolerances: []NumericTolerance{{Path: "a[x]"}}})
+	assert.NotNil(t, err)
+}
+
+func TestNewCmpOptionsStringNumberEqual(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+	var data3 interface{}
+
+	err := json.Unmarshal([]byte(`{"id": "123", "price": "1.50", "name": "1"}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"id": 123, "price": 1.5, "name": "1"}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"id": 123, "price": 1.5, "name": "1.0"}`), &data3)
+	if err != nil {
+		panic(err)
+	}
+
+	options, err := NewCmpOptions(CompareOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", cmp.Diff(data1, data2, options...))
+
+	options, err = NewCmpOptions(CompareOptions{StringNumberEqual: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "", cmp.Diff(data1, data2, options...))
+
+	// 两边都是字符串时不做转换
+	assert.NotEqual(t, "", cmp.Diff(data1, data3, options...))
+}
+
+func TestCmpPathToJsonPath(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	_ = json.Unmarshal([]byte(`{"data": {"items": [{"id": 1}]}}`), &data1)
+	_ = json.Unmarshal([]byte(`{"data": {"items": [{"id": 2}]}}`), &data2)
+
+	paths := make([]string, 0)
+	cmp.Equal(data1, data2, cmp.FilterPath(func(path cmp.Path) bool {
+		if _, ok := path.Last().(cmp.MapIndex); ok {
+			paths = append(paths, CmpPathToJsonPath(path))
+		}
+		return false
+	}, cmp.Ignore()))
+
+	assert.Contains(t, paths, "$.data.items[0].id")
+}
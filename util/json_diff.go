@@ -0,0 +1,95 @@
+package util
+
+import (
+	"reflect"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	// DiffKindAdded 只在 B 中存在
+	DiffKindAdded = "added"
+	// DiffKindRemoved 只在 A 中存在
+	DiffKindRemoved = "removed"
+	// DiffKindChanged 类型相同但值不同
+	DiffKindChanged = "changed"
+	// DiffKindTypeChanged 类型不同
+	DiffKindTypeChanged = "type-changed"
+)
+
+// Difference 结构化的差异
+type Difference struct {
+	// Path 差异所在的 JSON 路径，例如 $.data.items[0].id
+	Path string `json:"path"`
+	// Kind 差异类型 added、removed、changed、type-changed
+	Kind string `json:"kind"`
+	// ValueA A 中的值
+	ValueA interface{} `json:"valueA,omitempty"`
+	// ValueB B 中的值
+	ValueB interface{} `json:"valueB,omitempty"`
+}
+
+// DiffJson 对比两个 JSON 数据，返回 cmp.Diff 的文本结果和结构化的差异列表
+func DiffJson(a, b interface{}, opts ...cmp.Option) (string, []Difference) {
+	reporter := &differenceReporter{}
+
+	options := make([]cmp.Option, 0, len(opts)+1)
+	options = append(options, opts...)
+	options = append(options, cmp.Reporter(reporter))
+
+	diff := cmp.Diff(a, b, options...)
+	if diff == "" {
+		return "", nil
+	}
+
+	return diff, reporter.differences
+}
+
+// differenceReporter 收集 cmp 对比过程中不相等的叶子节点
+type differenceReporter struct {
+	path        cmp.Path
+	differences []Difference
+}
+
+func (r *differenceReporter) PushStep(step cmp.PathStep) {
+	r.path = append(r.path, step)
+}
+
+func (r *differenceReporter) Report(result cmp.Result) {
+	if result.Equal() {
+		return
+	}
+
+	valueA, valueB := r.path.Last().Values()
+	difference := Difference{Path: CmpPathToJsonPath(r.path)}
+
+	switch {
+	case !valueA.IsValid():
+		difference.Kind = DiffKindAdded
+		difference.ValueB = reflectValue(valueB)
+	case !valueB.IsValid():
+		difference.Kind = DiffKindRemoved
+		difference.ValueA = reflectValue(valueA)
+	default:
+		difference.ValueA = reflectValue(valueA)
+		difference.ValueB = reflectValue(valueB)
+		difference.Kind = DiffKindChanged
+		if reflect.TypeOf(difference.ValueA) != reflect.TypeOf(difference.ValueB) {
+			difference.Kind = DiffKindTypeChanged
+		}
+	}
+
+	r.differences = append(r.differences, difference)
+}
+
+func (r *differenceReporter) PopStep() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+func reflectValue(value reflect.Value) interface{} {
+	if !value.IsValid() || !value.CanInterface() {
+		return nil
+	}
+
+	return value.Interface()
+}
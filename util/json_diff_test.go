@@ -0,0 +1,54 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffJson(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	err := json.Unmarshal([]byte(`{"name": "a", "age": 18, "id": 1, "tags": ["x"], "old": true}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"name": "b", "age": "18", "id": 1, "tags": ["x", "y"], "new": 1}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	diff, differences := DiffJson(data1, data2)
+	assert.NotEqual(t, "", diff)
+	assert.ElementsMatch(t, []Difference{
+		{Path: "$.age", Kind: DiffKindTypeChanged, ValueA: float64(18), ValueB: "18"},
+		{Path: "$.name", Kind: DiffKindChanged, ValueA: "a", ValueB: "b"},
+		{Path: "$.new", Kind: DiffKindAdded, ValueB: float64(1)},
+		{Path: "$.old", Kind: DiffKindRemoved, ValueA: true},
+		{Path: "$.tags[1]", Kind: DiffKindAdded, ValueB: "y"},
+	}, differences)
+
+	diff, differences = DiffJson(data1, data1)
+	assert.Equal(t, "", diff)
+	assert.Nil(t, differences)
+}
+
+func TestDiffJsonKeyedArray(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	_ = json.Unmarshal([]byte(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}`), &data1)
+	_ = json.Unmarshal([]byte(`{"items": [{"id": 2, "name": "c"}, {"id": 1, "name": "a"}]}`), &data2)
+
+	rules := []UnorderedArrayRule{{Path: "items", Key: "id"}}
+	_, err := NormalizeUnorderedArrays(data1, rules)
+	assert.Nil(t, err)
+	_, err = NormalizeUnorderedArrays(data2, rules)
+	assert.Nil(t, err)
+
+	_, differences := DiffJson(data1, data2)
+	assert.Equal(t, []Difference{{Path: "$.items[id=2].name", Kind: DiffKindChanged, ValueA: "b", ValueB: "c"}}, differences)
+}
@@ -0,0 +1,482 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JsonNode 通过路径表达式匹配到的节点，记录节点所在的容器和原来的值，用于修改之后恢复
+type JsonNode struct {
+	// container 节点所在的容器，map[string]interface{} 或 []interface{}
+	container interface{}
+	// key 节点在 map 中的 key
+	key string
+	// index 节点在数组中的下标
+	index int
+	// value 节点原来的值
+	value interface{}
+	// exists 节点原来是否存在
+	exists bool
+	// path 节点的完整路径，例如 $.data.items[0].id
+	path string
+}
+
+// Path 节点的完整路径，例如 $.data.items[0].id
+func (n *JsonNode) Path() string {
+	return n.path
+}
+
+// Value 节点原来的值
+func (n *JsonNode) Value() interface{} {
+	return n.value
+}
+
+// Exists 节点原来是否存在
+func (n *JsonNode) Exists() bool {
+	return n.exists
+}
+
+// Set 设置节点的值
+func (n *JsonNode) Set(value interface{}) {
+	switch container := n.container.(type) {
+	case map[string]interface{}:
+		container[n.key] = value
+	case []interface{}:
+		container[n.index] = value
+	}
+}
+
+// Restore 恢复节点原来的值，原来不存在的 key 会被删除
+func (n *JsonNode) Restore() {
+	if m, ok := n.container.(map[string]interface{}); ok && !n.exists {
+		delete(m, n.key)
+		return
+	}
+
+	n.Set(n.value)
+}
+
+// SetJsonPathToNil 把路径表达式匹配到的所有节点设置为 nil，返回匹配到的节点用于恢复
+//
+// 路径表达式支持以下语法，开头的 $ 可以省略：
+//
+//	a.b        map 中的属性，最后一级属性不存在时也会被设置为 nil，保证两边的数据结构一致
+//	a.*        map 中的所有属性或数组中的所有元素
+//	a[0]       数组中的元素，负数表示从后往前数
+//	a[*]       数组中的所有元素
+//	a..b       任意层级的属性 b
+func SetJsonPathToNil(jsonData interface{}, expression string) ([]*JsonNode, error) {
+	nodes, err := FindJsonNodes(jsonData, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		node.Set(nil)
+	}
+
+	return nodes, nil
+}
+
+// RestoreJsonNodes 按照和匹配相反的顺序恢复节点原来的值
+func RestoreJsonNodes(nodes []*JsonNode) {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i].Restore()
+	}
+}
+
+// FindJsonNodes 查找路径表达式匹配到的所有节点，语法见 SetJsonPathToNil
+func FindJsonNodes(jsonData interface{}, expression string) ([]*JsonNode, error) {
+	segments, err := parseJsonPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	values := []jsonValue{{value: jsonData, path: "$"}}
+	var nodes []*JsonNode
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		if segment.recursive {
+			values = descendants(values)
+		}
+
+		nodes = nodes[:0:0]
+		for _, value := range values {
+			nodes = append(nodes, segment.match(value, last)...)
+		}
+
+		values = make([]jsonValue, 0, len(nodes))
+		for _, node := range nodes {
+			if node.exists {
+				values = append(values, jsonValue{value: node.value, path: node.path})
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// SetJsonPath 设置路径表达式对应的值，路径中不存在的属性会被创建为对象
+//
+// 只支持属性和数组下标，不支持 * 和 ..，数组下标超出范围或者上级节点不是对象和数组时返回错误
+func SetJsonPath(jsonData interface{}, expression string, value interface{}) error {
+	segments, err := parseJsonPath(expression)
+	if err != nil {
+		return err
+	}
+
+	current := jsonData
+	for i, segment := range segments {
+		if segment.recursive || segment.wildcard {
+			return errors.New("json path cannot contain * or .. [" + expression + "]")
+		}
+
+		last := i == len(segments)-1
+
+		switch container := current.(type) {
+		case map[string]interface{}:
+			if segment.isIndex {
+				return errors.New("json path index on object [" + expression + "]")
+			}
+
+			if last {
+				container[segment.key] = value
+				return nil
+			}
+
+			child, exists := container[segment.key]
+			if !exists || child == nil {
+				child = make(map[string]interface{})
+				container[segment.key] = child
+			}
+			current = child
+		case []interface{}:
+			if !segment.isIndex {
+				return errors.New("json path field on array [" + expression + "]")
+			}
+
+			index := segment.index
+			if index < 0 {
+				index = len(container) + index
+			}
+
+			if index < 0 || index >= len(container) {
+				return errors.New("json path index out of range [" + expression + "]")
+			}
+
+			if last {
+				container[index] = value
+				return nil
+			}
+			current = container[index]
+		default:
+			return errors.New("json path parent is not an object or array [" + expression + "]")
+		}
+	}
+
+	return nil
+}
+
+// DeleteJsonPath 删除路径表达式匹配到的对象属性，数组元素不会被删除，返回删除的属性数量
+func DeleteJsonPath(jsonData interface{}, expression string) (int, error) {
+	nodes, err := FindJsonNodes(jsonData, expression)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, node := range nodes {
+		if m, ok := node.container.(map[string]interface{}); ok && node.exists {
+			delete(m, node.key)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// RenameJsonPath 把路径表达式匹配到的对象属性重命名为 newKey，返回重命名的属性数量
+func RenameJsonPath(jsonData interface{}, expression string, newKey string) (int, error) {
+	nodes, err := FindJsonNodes(jsonData, expression)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, node := range nodes {
+		if m, ok := node.container.(map[string]interface{}); ok && node.exists && node.key != newKey {
+			delete(m, node.key)
+			m[newKey] = node.value
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// jsonValue 带路径的节点值
+type jsonValue struct {
+	value interface{}
+	path  string
+}
+
+type jsonPathSegment struct {
+	// recursive 是否匹配任意层级
+	recursive bool
+	// wildcard 是否匹配所有属性或元素
+	wildcard bool
+	// isIndex 是否为数组下标
+	isIndex bool
+	key     string
+	index   int
+}
+
+// match 在 value 中查找匹配的节点，createMissing 为 true 时 map 中不存在的属性也会被匹配
+func (s jsonPathSegment) match(value jsonValue, createMissing bool) []*JsonNode {
+	switch container := value.value.(type) {
+	case map[string]interface{}:
+		if s.isIndex {
+			return nil
+		}
+
+		if s.wildcard {
+			keys := make([]string, 0, len(container))
+			for key := range container {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			nodes := make([]*JsonNode, 0, len(keys))
+			for _, key := range keys {
+				nodes = append(nodes, &JsonNode{container: container, key: key, value: container[key], exists: true, path: ChildKeyPath(value.path, key)})
+			}
+			return nodes
+		}
+
+		fieldValue, exists := container[s.key]
+		if !exists && (!createMissing || s.recursive) {
+			return nil
+		}
+
+		return []*JsonNode{{container: container, key: s.key, value: fieldValue, exists: exists, path: ChildKeyPath(value.path, s.key)}}
+	case []interface{}:
+		if s.wildcard {
+			nodes := make([]*JsonNode, 0, len(container))
+			for index, element := range container {
+				nodes = append(nodes, &JsonNode{container: container, index: index, value: element, exists: true, path: ChildIndexPath(value.path, index)})
+			}
+			return nodes
+		}
+
+		if !s.isIndex {
+			return nil
+		}
+
+		index := s.index
+		if index < 0 {
+			index = len(container) + index
+		}
+
+		if index < 0 || index >= len(container) {
+			return nil
+		}
+
+		return []*JsonNode{{container: container, index: index, value: container[index], exists: true, path: ChildIndexPath(value.path, index)}}
+	default:
+		return nil
+	}
+}
+
+// descendants 返回 values 自身以及所有层级的子节点
+func descendants(values []jsonValue) []jsonValue {
+	result := make([]jsonValue, 0, len(values))
+
+	var walk func(value jsonValue)
+	walk = func(value jsonValue) {
+		result = append(result, value)
+
+		switch container := value.value.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(container))
+			for key := range container {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				walk(jsonValue{value: container[key], path: ChildKeyPath(value.path, key)})
+			}
+		case []interface{}:
+			for index, element := range container {
+				walk(jsonValue{value: element, path: ChildIndexPath(value.path, index)})
+			}
+		}
+	}
+
+	for _, value := range values {
+		walk(value)
+	}
+
+	return result
+}
+
+// NormalizeJsonPath 把具体的 JSON 路径中的数组下标和无序数组的 key 替换为 [*]，用于按路径汇总差异
+//
+// 例如 $.data.items[0].id 和 $.data.items[id=1].id 都会被转换为 $.data.items[*].id
+func NormalizeJsonPath(path string) string {
+	builder := strings.Builder{}
+	for {
+		start := strings.Index(path, "[")
+		if start < 0 {
+			builder.WriteString(path)
+			break
+		}
+
+		end := strings.Index(path[start:], "]")
+		if end < 0 {
+			builder.WriteString(path)
+			break
+		}
+		end += start
+
+		content := path[start+1 : end]
+		builder.WriteString(path[:start])
+		if strings.HasPrefix(content, "'") {
+			builder.WriteString(path[start : end+1])
+		} else {
+			builder.WriteString("[*]")
+		}
+
+		path = path[end+1:]
+	}
+
+	return builder.String()
+}
+
+// ChildKeyPath 子属性的路径，属性名包含特殊字符时使用 ['key'] 的形式
+func ChildKeyPath(parent, key string) string {
+	if key == "" || strings.ContainsAny(key, ".[]'\" *") {
+		return parent + "['" + key + "']"
+	}
+
+	return parent + "." + key
+}
+
+// ChildIndexPath 数组元素的路径
+func ChildIndexPath(parent string, index int) string {
+	return parent + "[" + strconv.Itoa(index) + "]"
+}
+
+// parseJsonPath 解析路径表达式
+//
+//nolint:gocyclo
+func parseJsonPath(expression string) ([]jsonPathSegment, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, errors.New("json path cannot be empty")
+	}
+
+	rest := strings.TrimPrefix(expression, "$")
+	// 兼容 a.b 形式的写法
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+
+	segments := make([]jsonPathSegment, 0)
+	recursive := false
+	for rest != "" {
+		if strings.HasPrefix(rest, "..") {
+			recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				continue
+			}
+		} else if rest[0] == '.' {
+			rest = rest[1:]
+		}
+
+		segment := jsonPathSegment{recursive: recursive}
+		recursive = false
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, errors.New("json path bracket is not closed [" + expression + "]")
+			}
+
+			content := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case content == "*":
+				segment.wildcard = true
+			case len(content) >= 2 && (content[0] == '\'' || content[0] == '"') && content[len(content)-1] == content[0]:
+				segment.key = content[1 : len(content)-1]
+			default:
+				index, err := strconv.Atoi(content)
+				if err != nil {
+					return nil, errors.New("json path index is invalid [" + expression + "]")
+				}
+				segment.isIndex = true
+				segment.index = index
+			}
+
+			segments = append(segments, segment)
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+
+		name := rest[:end]
+		rest = rest[end:]
+		if name == "" {
+			return nil, errors.New("json path field name cannot be empty [" + expression + "]")
+		}
+
+		if name == "*" {
+			segment.wildcard = true
+		} else {
+			segment.key = name
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if recursive {
+		return nil, errors.New("json path cannot end with .. [" + expression + "]")
+	}
+
+	if len(segments) == 0 {
+		return nil, errors.New("json path cannot be root [" + expression + "]")
+	}
+
+	return segments, nil
+}
+
+// JsonPathAncestors 返回路径的所有上级路径，从根路径 $ 开始，不包含路径本身
+//
+// 例如 $.a.b[0] 返回 $、$.a、$.a.b
+func JsonPathAncestors(path string) []string {
+	result := make([]string, 0)
+	inQuote := false
+
+	for i := 1; i < len(path); i++ {
+		switch path[i] {
+		case '\'':
+			inQuote = !inQuote
+		case '.', '[':
+			if !inQuote && path[i-1] != '.' {
+				result = append(result, path[:i])
+			}
+		}
+	}
+
+	return result
+}
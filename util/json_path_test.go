@@ -0,0 +1,181 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetJsonPathToNilArrayElements(t *testing.T) {
+	var data1 interface{}
+	var data2 interface{}
+
+	err := json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "updatedAt": "a"}, {"id": 2, "updatedAt": "b"}]}}`), &data1)
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "updatedAt": "c"}, {"id": 2}]}}`), &data2)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.NotEqual(t, "", cmp.Diff(data1, data2))
+
+	nodes1, err := SetJsonPathToNil(data1, "data.items[*].updatedAt")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(nodes1))
+
+	nodes2, err := SetJsonPathToNil(data2, "$.data.items[*].updatedAt")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(nodes2))
+
+	assert.Equal(t, "", cmp.Diff(data1, data2))
+
+	RestoreJsonNodes(nodes1)
+	RestoreJsonNodes(nodes2)
+
+	var expected1 interface{}
+	var expected2 interface{}
+	_ = json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "updatedAt": "a"}, {"id": 2, "updatedAt": "b"}]}}`), &expected1)
+	_ = json.Unmarshal([]byte(`{"data": {"items": [{"id": 1, "updatedAt": "c"}, {"id": 2}]}}`), &expected2)
+	assert.Equal(t, "", cmp.Diff(expected1, data1))
+	assert.Equal(t, "", cmp.Diff(expected2, data2))
+}
+
+func TestSetJsonPathToNilIndex(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"items": [1, 2, 3]}`), &data)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = SetJsonPathToNil(data, "items[0]")
+	assert.Nil(t, err)
+
+	_, err = SetJsonPathToNil(data, "items[-1]")
+	assert.Nil(t, err)
+
+	nodes, err := SetJsonPathToNil(data, "items[10]")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(nodes))
+
+	assert.Equal(t, []interface{}{nil, float64(2), nil}, data.(map[string]interface{})["items"])
+}
+
+func TestSetJsonPathToNilRecursive(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"traceId": "1", "data": {"traceId": "2", "list": [{"traceId": "3"}, {"name": "a"}]}}`), &data)
+	if err != nil {
+		panic(err)
+	}
+
+	nodes, err := SetJsonPathToNil(data, "..traceId")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(nodes))
+
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{"traceId": null, "data": {"traceId": null, "list": [{"traceId": null}, {"name": "a"}]}}`), &expected)
+	assert.Equal(t, "", cmp.Diff(expected, data))
+}
+
+func TestSetJsonPathToNilWildcardKey(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"users": {"a": {"age": 1, "name": "a"}, "b": {"age": 2, "name": "b"}}}`), &data)
+	if err != nil {
+		panic(err)
+	}
+
+	nodes, err := SetJsonPathToNil(data, "users.*.age")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(nodes))
+
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{"users": {"a": {"age": null, "name": "a"}, "b": {"age": null, "name": "b"}}}`), &expected)
+	assert.Equal(t, "", cmp.Diff(expected, data))
+}
+
+func TestParseJsonPathInvalid(t *testing.T) {
+	_, err := FindJsonNodes(nil, "")
+	assert.NotNil(t, err)
+
+	_, err = FindJsonNodes(nil, "$")
+	assert.NotNil(t, err)
+
+	_, err = FindJsonNodes(nil, "a[1")
+	assert.NotNil(t, err)
+
+	_, err = FindJsonNodes(nil, "a[x]")
+	assert.NotNil(t, err)
+
+	_, err = FindJsonNodes(nil, "a..")
+	assert.NotNil(t, err)
+}
+
+func TestNormalizeJsonPath(t *testing.T) {
+	assert.Equal(t, "$.data.items[*].id", NormalizeJsonPath("$.data.items[0].id"))
+	assert.Equal(t, "$.data.items[*].tags[*]", NormalizeJsonPath("$.data.items[id=1].tags[12]"))
+	assert.Equal(t, "$['a.b'][*]", NormalizeJsonPath("$['a.b'][3]"))
+	assert.Equal(t, "$.name", NormalizeJsonPath("$.name"))
+}
+
+func TestJsonPathAncestors(t *testing.T) {
+	assert.Equal(t, []string{"$", "$.a", "$.a.b"}, JsonPathAncestors("$.a.b[0]"))
+	assert.Equal(t, []string{"$", "$['a.b']"}, JsonPathAncestors("$['a.b'].c"))
+	assert.Equal(t, []string{"$", "$.items"}, JsonPathAncestors("$.items[id=1]"))
+	assert.Equal(t, []string{}, JsonPathAncestors("$"))
+}
+
+func TestSetJsonPath(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"user": {"id": 1}, "items": [{"id": 1}], "name": "a"}`), &data)
+	if err != nil {
+		panic(err)
+	}
+
+	assert.Nil(t, SetJsonPath(data, "user.name", "b"))
+	assert.Nil(t, SetJsonPath(data, "$.meta.version", float64(2)))
+	assert.Nil(t, SetJsonPath(data, "items[0].name", "c"))
+	assert.Nil(t, SetJsonPath(data, "items[-1].id", float64(3)))
+
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{"user": {"id": 1, "name": "b"}, "meta": {"version": 2}, "items": [{"id": 3, "name": "c"}], "name": "a"}`), &expected)
+	assert.Equal(t, "", cmp.Diff(expected, data))
+
+	assert.NotNil(t, SetJsonPath(data, "items[1].id", 1))
+	assert.NotNil(t, SetJsonPath(data, "items.id", 1))
+	assert.NotNil(t, SetJsonPath(data, "name.first", 1))
+	assert.NotNil(t, SetJsonPath(data, "items[*].id", 1))
+	assert.NotNil(t, SetJsonPath(data, "..id", 1))
+}
+
+func TestDeleteAndRenameJsonPath(t *testing.T) {
+	var data interface{}
+	err := json.Unmarshal([]byte(`{"uid": 1, "items": [{"uid": 2, "v": 1}, {"uid": 3}], "list": [1, 2]}`), &data)
+	if err != nil {
+		panic(err)
+	}
+
+	count, err := RenameJsonPath(data, "$..uid", "userId")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = DeleteJsonPath(data, "items[*].v")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+
+	// 数组元素和不存在的属性不会被删除
+	count, err = DeleteJsonPath(data, "list[0]")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = DeleteJsonPath(data, "notExist")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	var expected interface{}
+	_ = json.Unmarshal([]byte(`{"userId": 1, "items": [{"userId": 2}, {"userId": 3}], "list": [1, 2]}`), &expected)
+	assert.Equal(t, "", cmp.Diff(expected, data))
+}
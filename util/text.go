@@ -0,0 +1,142 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+// textDiffMaxEdits 按行对比时最多计算的增删行数，超过之后只返回行数的汇总，避免响应体很大并且差异很多时占用过多的内存和时间
+const textDiffMaxEdits = 1000
+
+// TextDiff 按行对比两段文本，返回差异内容，没有差异时返回空字符串
+//
+// 只在 a 中存在的行以 "- " 开头，只在 b 中存在的行以 "+ " 开头。使用 Myers 算法，增删的行数超过 textDiffMaxEdits 时只返回两段文本的行数
+func TextDiff(a, b string) string {
+	if a == b {
+		return ""
+	}
+
+	linesA := strings.Split(a, "\n")
+	linesB := strings.Split(b, "\n")
+
+	// 跳过相同的前缀和后缀，减少需要对比的行数
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix && linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	diff, ok := myersDiff(linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix], textDiffMaxEdits)
+	if !ok {
+		return "- lines: " + strconv.Itoa(len(linesA)) + ", too many differences from line " + strconv.Itoa(prefix+1) + "\n" +
+			"+ lines: " + strconv.Itoa(len(linesB)) + ", too many differences from line " + strconv.Itoa(prefix+1) + "\n"
+	}
+
+	return diff
+}
+
+// myersDiff 使用 Myers 算法计算最少的增删行，增删的行数超过 maxEdits 时返回 false
+//
+// 每一步只保存当前可以到达的对角线，内存占用和增删行数的平方成正比，和文本的行数无关
+func myersDiff(linesA, linesB []string, maxEdits int) (string, bool) {
+	n, m := len(linesA), len(linesB)
+	maxEdits = min(maxEdits, n+m)
+
+	// v[offset+k] 为对角线 k 上能到达的最远的 x，y = x - k
+	offset := maxEdits + 1
+	v := make([]int, 2*maxEdits+3)
+	// trace[d] 为第 d 步之后对角线 -d 到 d 的 v
+	trace := make([][]int, 0)
+
+	found := false
+	for d := 0; d <= maxEdits && !found; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && linesA[x] == linesB[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+	}
+
+	if !found {
+		return "", false
+	}
+
+	// 从终点回溯，得到倒序的增删行
+	edits := make([]string, 0, len(trace))
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d-1]
+		k := x - y
+
+		var previousK int
+		if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+
+		previousX := previous[previousK+d-1]
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			x--
+			y--
+		}
+
+		if x == previousX {
+			edits = append(edits, "+ "+linesB[previousY]+"\n")
+		} else {
+			edits = append(edits, "- "+linesA[previousX]+"\n")
+		}
+
+		x, y = previousX, previousY
+	}
+
+	builder := strings.Builder{}
+	for i := len(edits) - 1; i >= 0; i-- {
+		builder.WriteString(edits[i])
+	}
+
+	return builder.String(), true
+}
+
+// SplitEscaped 按照没有转义的 : 把文本最多分成 n 段，最后一段包含剩余的所有文本，\: 转换为 :
+func SplitEscaped(text string, n int) []string {
+	parts := make([]string, 0, n)
+	builder := strings.Builder{}
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && text[i+1] == ':':
+			builder.WriteByte(':')
+			i++
+		case text[i] == ':' && len(parts) < n-1:
+			parts = append(parts, builder.String())
+			builder.Reset()
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+
+	return append(parts, builder.String())
+}
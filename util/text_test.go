@@ -0,0 +1,63 @@
+package util
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTextDiff(t *testing.T) {
+	assert.Equal(t, "", TextDiff("a\nb\nc", "a\nb\nc"))
+
+	diff := TextDiff("a\nb\nc", "a\nd\nc")
+	assert.Equal(t, "- b\n+ d\n", diff)
+
+	diff = TextDiff("a\nb", "a\nb\nc")
+	assert.Equal(t, "+ c\n", diff)
+
+	diff = TextDiff("a\nb\nc", "b\nc")
+	assert.Equal(t, "- a\n", diff)
+}
+
+func TestTextDiffLargeText(t *testing.T) {
+	linesA := make([]string, 0, 200000)
+	for i := 0; i < 200000; i++ {
+		linesA = append(linesA, "line"+strconv.Itoa(i))
+	}
+
+	linesB := append([]string(nil), linesA...)
+	linesB[1000] = "changed"
+	linesB = append(linesB[:5000], linesB[5001:]...)
+
+	diff := TextDiff(strings.Join(linesA, "\n"), strings.Join(linesB, "\n"))
+	assert.Equal(t, "- line1000\n+ changed\n- line5000\n", diff)
+
+	// 差异过多时只返回行数
+	for i := range linesB {
+		linesB[i] = "other" + strconv.Itoa(i)
+	}
+	diff = TextDiff(strings.Join(linesA, "\n"), strings.Join(linesB, "\n"))
+	assert.Equal(t, "- lines: 200000, too many differences from line 1\n+ lines: 199999, too many differences from line 1\n", diff)
+}
+
+func TestSplitEscaped(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want []string
+	}{
+		{text: "a:b:c", n: 3, want: []string{"a", "b", "c"}},
+		{text: "a:b:c:d", n: 3, want: []string{"a", "b", "c:d"}},
+		{text: "a\\:b:c", n: 3, want: []string{"a:b", "c"}},
+		{text: "a:b\\:c", n: 2, want: []string{"a", "b:c"}},
+		{text: "a\\b", n: 2, want: []string{"a\\b"}},
+		{text: "", n: 2, want: []string{""}},
+		{text: "a:", n: 2, want: []string{"a", ""}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SplitEscaped(tt.text, tt.n), tt.text)
+	}
+}
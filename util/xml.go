@@ -0,0 +1,74 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CanonicalXml 把 XML 转换为规范格式，每个节点一行并按层级缩进
+//
+// 属性按名字排序，文本去掉首尾空白，忽略注释、处理指令和文档类型声明，便于按行对比
+func CanonicalXml(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	builder := strings.Builder{}
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			attrs := make([]xml.Attr, len(element.Attr))
+			copy(attrs, element.Attr)
+			sort.Slice(attrs, func(i, j int) bool {
+				return xmlName(attrs[i].Name) < xmlName(attrs[j].Name)
+			})
+
+			builder.WriteString(strings.Repeat("  ", depth) + "<" + xmlName(element.Name))
+			for _, attr := range attrs {
+				builder.WriteString(" " + xmlName(attr.Name) + "=\"" + escapeXml(attr.Value) + "\"")
+			}
+			builder.WriteString(">\n")
+			depth++
+		case xml.EndElement:
+			depth--
+			builder.WriteString(strings.Repeat("  ", depth) + "</" + xmlName(element.Name) + ">\n")
+		case xml.CharData:
+			text := strings.TrimSpace(string(element))
+			if text != "" {
+				builder.WriteString(strings.Repeat("  ", depth) + escapeXml(text) + "\n")
+			}
+		}
+	}
+
+	if depth != 0 {
+		return "", errors.New("xml element is not closed")
+	}
+
+	return builder.String(), nil
+}
+
+func xmlName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
+
+func escapeXml(text string) string {
+	buffer := bytes.Buffer{}
+	_ = xml.EscapeText(&buffer, []byte(text))
+	return buffer.String()
+}
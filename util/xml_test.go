@@ -0,0 +1,26 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalXml(t *testing.T) {
+	xmlA := `<?xml version="1.0"?><user id="1" name="a"><!-- comment --><age>18</age></user>`
+	xmlB := `<user name="a" id="1">
+    <age> 18 </age>
+</user>`
+
+	canonicalA, err := CanonicalXml([]byte(xmlA))
+	assert.Nil(t, err)
+
+	canonicalB, err := CanonicalXml([]byte(xmlB))
+	assert.Nil(t, err)
+
+	assert.Equal(t, "<user id=\"1\" name=\"a\">\n  <age>\n    18\n  </age>\n</user>\n", canonicalA)
+	assert.Equal(t, canonicalA, canonicalB)
+
+	_, err = CanonicalXml([]byte(`<user><age>18</age>`))
+	assert.NotNil(t, err)
+}